@@ -1,19 +1,36 @@
 package main
 
 import (
+	"archive/zip"
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"database/sql"
 	"encoding/base64"
+	"encoding/csv"
+	"encoding/hex"
 	"encoding/json"
+	"encoding/xml"
 	"errors"
 	"fmt"
+	"html"
+	"io"
 	"log"
+	"math"
+	"math/rand"
+	"net/url"
 	"os"
+	"path"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
+	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 
 	firebase "firebase.google.com/go"
@@ -21,7 +38,7 @@ import (
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 	"github.com/xuri/excelize/v2"
 )
 
@@ -83,19 +100,508 @@ var (
 	DBPort     = os.Getenv("POSTGRESQL_PORT")
 )
 
+// ✅ Standby PostgreSQL for cross-region failover. DB_FAILOVER_TARGET=standby
+// binds every connection to the standby host; credentials and database name
+// are shared with the primary.
+var (
+	DBStandbyHost    = os.Getenv("POSTGRESQL_STANDBY_HOST")
+	DBStandbyPort    = os.Getenv("POSTGRESQL_STANDBY_PORT")
+	DBFailoverTarget = os.Getenv("DB_FAILOVER_TARGET")
+)
+
+// ✅ Database Targets
+const (
+	DBTargetPrimary = "primary"
+	DBTargetStandby = "standby"
+)
+
+// ✅ Upload Limits (overridable via env)
+var (
+	MaxUploadBytes    = getEnvInt("UPLOAD_MAX_BYTES", 4*1024*1024)
+	MaxUploadRows     = getEnvInt("UPLOAD_MAX_ROWS", 5000)
+	MaxQuestionLength = getEnvInt("UPLOAD_MAX_QUESTION_LENGTH", 2000)
+	// Above this many rows a sheet skips rich text, hyperlinks and merged
+	// passages: excelize decodes the whole worksheet to look any of them up
+	MaxRichSheetRows = getEnvInt("UPLOAD_RICH_MAX_ROWS", 500)
+)
+
+// Helper function to read a positive integer env var with a default
+func getEnvInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || value <= 0 {
+		return defaultValue
+	}
+	return value
+}
+
+// ✅ Valid Quiz Categories (VALID_CATEGORIES env var, comma-separated).
+// Must mirror the list the quiz-listing service filters on; when unset,
+// categories are not validated.
+var validCategories = getEnvList("VALID_CATEGORIES")
+
+// Helper function to read a comma-separated env var, skipping blank entries
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// ✅ Daily Quiz Categories (DAILY_QUIZ_CATEGORIES env var, comma-separated): the
+// categories the app filters by publish date, so each day needs its own quiz
+var dailyQuizCategories = getEnvList("DAILY_QUIZ_CATEGORIES")
+
+// ✅ Timezone for calendar days (CALENDAR_TIMEZONE env var, default Asia/Kolkata)
+var calendarLocation = loadCalendarLocation()
+
+func loadCalendarLocation() *time.Location {
+	name := os.Getenv("CALENDAR_TIMEZONE")
+	if name == "" {
+		name = "Asia/Kolkata"
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		log.Printf("⚠️ Unknown CALENDAR_TIMEZONE %q, using UTC: %v", name, err)
+		return time.UTC
+	}
+	return location
+}
+
+// ✅ Delimiter between options in an IncorrectAnswers cell (INCORRECT_ANSWERS_DELIMITER env var)
+var incorrectAnswersDelimiter = loadIncorrectAnswersDelimiter()
+
+func loadIncorrectAnswersDelimiter() string {
+	if delimiter := os.Getenv("INCORRECT_ANSWERS_DELIMITER"); delimiter != "" {
+		return delimiter
+	}
+	return ","
+}
+
+// ✅ Excel Header Aliases (HEADER_ALIASES env var, JSON {"alias": "Column"})
+var headerAliases = loadHeaderAliases()
+
+func loadHeaderAliases() map[string]string {
+	aliases := map[string]string{
+		"q":         "Question",
+		"questions": "Question",
+		"answer":    "CorrectAnswer",
+		"correct":   "CorrectAnswer",
+		"reference": "ReferenceURL",
+		"link":      "ReferenceURL",
+	}
+
+	aliasesJSON := os.Getenv("HEADER_ALIASES")
+	if aliasesJSON == "" {
+		return aliases
+	}
+
+	var configured map[string]string
+	if err := json.Unmarshal([]byte(aliasesJSON), &configured); err != nil {
+		log.Printf("⚠️ Ignoring invalid HEADER_ALIASES: %v", err)
+		return aliases
+	}
+	for alias, column := range configured {
+		aliases[strings.ToLower(strings.TrimSpace(alias))] = column
+	}
+	return aliases
+}
+
+// Columns the parser understands, used to match misspelled headers
+var knownColumns = []string{
+	"Question", "CorrectAnswer", "IncorrectAnswers", "Explanation", "Type", "Marks", "TimeSeconds",
+	"Latex", "ReferenceURL", "SubQuestion", "Difficulty",
+	"OptionA", "OptionB", "OptionC", "OptionD", "OptionE", "CorrectOption",
+}
+
+// Helper function to compare headers ignoring case, spaces, '_' and '-'
+func normalizeHeader(header string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || r == '_' || r == '-' {
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, header)
+}
+
+// ✅ Find the unused header closest to a missing column, if any is near enough
+// to be a typo (e.g. "Explaination")
+func closestHeader(header []string, headerMap map[string]int, column string) string {
+	used := make(map[int]bool)
+	for _, known := range knownColumns {
+		if index, exists := headerMap[known]; exists {
+			used[index] = true
+		}
+	}
+
+	target := normalizeHeader(column)
+	best, bestDistance := "", max(2, len(target)/3)+1
+	for i, name := range header {
+		if used[i] || strings.TrimSpace(name) == "" {
+			continue
+		}
+		if distance := levenshtein(normalizeHeader(name), target); distance < bestDistance {
+			best, bestDistance = strings.TrimSpace(name), distance
+		}
+	}
+	return best
+}
+
+// Helper function to map a sheet header to its canonical column name
+func canonicalHeader(header string) string {
+	header = strings.TrimSpace(header)
+	if column, exists := headerAliases[strings.ToLower(header)]; exists {
+		return column
+	}
+	return header
+}
+
 // ✅ Structs
 type QuizData struct {
-	QuizName  string     `json:"quizName"`
-	Duration  int        `json:"duration"`
-	Category  string     `json:"category"`
-	Questions []Question `json:"questions"`
+	QuizName     string         `json:"quizName"`
+	Duration     int            `json:"duration"`
+	Category     string         `json:"category"`
+	Instructions string         `json:"instructions,omitempty"`
+	Questions    []Question     `json:"questions"`
+	PublishAt    *time.Time     `json:"publishAt,omitempty"`
+	CloseAt      *time.Time     `json:"closeAt,omitempty"`
+	Metadata     QuizMetadata   `json:"metadata"`
+	Scoring      *ScoringConfig `json:"scoring,omitempty"`
+	FileHash     string         `json:"-"`
+}
+
+// ScoringConfig is a quiz's marking scheme. CorrectMarks applies to questions
+// without their own Marks; WrongPenalty is deducted for each wrong answer
+type ScoringConfig struct {
+	CorrectMarks    float64 `json:"correctMarks"`
+	WrongPenalty    float64 `json:"wrongPenalty"`
+	UnansweredMarks float64 `json:"unansweredMarks"`
+}
+
+// QuizMetadata narrows a quiz down within its category; stored as JSONB
+type QuizMetadata struct {
+	Topic    string   `json:"topic,omitempty"`
+	Chapter  string   `json:"chapter,omitempty"`
+	ExamYear int      `json:"examYear,omitempty"`
+	Author   string   `json:"author,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+}
+
+// QuizInfo is the pre-start view of a quiz (no questions)
+type QuizInfo struct {
+	QuizName      string         `json:"quizName"`
+	Category      string         `json:"category"`
+	Duration      int            `json:"duration"`
+	QuestionCount int            `json:"questionCount"`
+	Instructions  string         `json:"instructions"`
+	PublishAt     *time.Time     `json:"publishAt,omitempty"`
+	CloseAt       *time.Time     `json:"closeAt,omitempty"`
+	Scoring       *ScoringConfig `json:"scoring,omitempty"`
+}
+
+// QuizSummary is one row of GET /quizzes
+type QuizSummary struct {
+	QuizName      string       `json:"quizName"`
+	Category      string       `json:"category"`
+	Duration      int          `json:"duration"`
+	QuestionCount int          `json:"questionCount"`
+	Status        string       `json:"status"`
+	CreatedAt     time.Time    `json:"createdAt"`
+	PublishAt     *time.Time   `json:"publishAt,omitempty"`
+	CloseAt       *time.Time   `json:"closeAt,omitempty"`
+	Metadata      QuizMetadata `json:"metadata"`
 }
 
 type Question struct {
-	Explanation      string `json:"explanation"`
-	Question         string `json:"question"`
-	CorrectAnswer    string `json:"correctAnswer"`
-	IncorrectAnswers string `json:"incorrectAnswers"`
+	Explanation      string     `json:"explanation"`
+	Question         string     `json:"question"`
+	CorrectAnswer    string     `json:"correctAnswer"`
+	IncorrectAnswers AnswerList `json:"incorrectAnswers"`
+	Type             string     `json:"type"`
+	Marks            *float64   `json:"marks,omitempty"`
+	TimeSeconds      *int       `json:"timeSeconds,omitempty"`
+	Format           string     `json:"format,omitempty"`
+	Latex            string     `json:"latex,omitempty"`
+	ReferenceURL     string     `json:"referenceUrl,omitempty"`
+	Hint             string     `json:"hint,omitempty"`
+	Difficulty       string     `json:"difficulty,omitempty"`
+
+	Parts []QuestionPart `json:"parts,omitempty"`
+
+	Translations map[string]QuestionTranslation `json:"translations,omitempty"`
+
+	row int // source row, used for upload reports only
+}
+
+// QuestionTranslation holds one language's text, from columns like Question_TE
+type QuestionTranslation struct {
+	Question         string     `json:"question,omitempty"`
+	CorrectAnswer    string     `json:"correctAnswer,omitempty"`
+	IncorrectAnswers AnswerList `json:"incorrectAnswers,omitempty"`
+	Explanation      string     `json:"explanation,omitempty"`
+}
+
+// AnswerList holds a question's incorrect answers. Quizzes saved before answers
+// were split store one delimited string, which is still accepted when decoding.
+type AnswerList []string
+
+func (a *AnswerList) UnmarshalJSON(data []byte) error {
+	var answers []string
+	if err := json.Unmarshal(data, &answers); err == nil {
+		*a = answers
+		return nil
+	}
+
+	var legacy string
+	if err := json.Unmarshal(data, &legacy); err != nil {
+		return err
+	}
+	*a = splitAnswers(legacy)
+	return nil
+}
+
+// QuestionFormatHTML marks questions whose text fields are sanitized HTML
+// (kept from Excel rich text) rather than plain text
+const QuestionFormatHTML = "html"
+
+// ✅ Question Types (optional "Type" column, defaults to MCQ)
+const (
+	QuestionTypeMCQ         = "mcq"
+	QuestionTypeMultiSelect = "multi-select"
+	QuestionTypeTrueFalse   = "true-false"
+	QuestionTypeNumeric     = "numeric"
+)
+
+// QuestionTypePassage marks a passage question whose answers live in Parts;
+// it comes from merged Question cells, never from the Type column
+const QuestionTypePassage = "passage"
+
+// QuestionPart is one sub-question of a passage question, read from a row
+// under a merged Question cell
+type QuestionPart struct {
+	Question         string     `json:"question,omitempty"`
+	CorrectAnswer    string     `json:"correctAnswer"`
+	IncorrectAnswers AnswerList `json:"incorrectAnswers"`
+	Explanation      string     `json:"explanation"`
+	Type             string     `json:"type"`
+	Marks            *float64   `json:"marks,omitempty"`
+}
+
+// RowError describes one problem found in an uploaded sheet
+type RowError struct {
+	Sheet  string `json:"sheet,omitempty"`
+	Row    int    `json:"row"`
+	Column string `json:"column,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// UploadValidationError carries every row-level problem found in an upload
+type UploadValidationError struct {
+	Rows []RowError
+}
+
+func (e *UploadValidationError) Error() string {
+	return fmt.Sprintf("%d invalid row(s) in upload", len(e.Rows))
+}
+
+// UploadLimitError is returned when an upload exceeds a configured limit
+type UploadLimitError struct {
+	Message string
+}
+
+func (e *UploadLimitError) Error() string {
+	return e.Message
+}
+
+// SheetNotFoundError is returned when the requested sheet is not in the workbook
+type SheetNotFoundError struct {
+	Sheet     string
+	Available []string
+}
+
+func (e *SheetNotFoundError) Error() string {
+	return fmt.Sprintf("sheet %s not found", e.Sheet)
+}
+
+// UploadAuditEntry is one recorded /upload/questions call
+type UploadAuditEntry struct {
+	ID            int       `json:"id"`
+	UploaderEmail string    `json:"uploaderEmail"`
+	QuizName      string    `json:"quizName"`
+	Category      string    `json:"category"`
+	RowCount      int       `json:"rowCount"`
+	FileHash      string    `json:"fileHash"`
+	StatusCode    int       `json:"statusCode"`
+	Outcome       string    `json:"outcome"`
+	Message       string    `json:"message,omitempty"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+type ExamScheduleEntry struct {
+	ID          int    `json:"id"`
+	Date        string `json:"date"`
+	Category    string `json:"category"`
+	Description string `json:"description"`
+}
+
+// CalendarDay is one date of GET /admin/content-calendar that has quizzes
+type CalendarDay struct {
+	Date      string `json:"date"`
+	Published int    `json:"published"`
+	Drafts    int    `json:"drafts"`
+}
+
+// CategoryCalendar lists covered and uncovered dates for one category; a date
+// only counts as covered once a published quiz opens on it
+type CategoryCalendar struct {
+	Category string        `json:"category"`
+	Days     []CalendarDay `json:"days"`
+	Missing  []string      `json:"missing"`
+}
+
+// ContentChange is one entry of the GET /changes feed
+type ContentChange struct {
+	ID        int64     `json:"id"`
+	Type      string    `json:"type"`
+	QuizName  string    `json:"quizName"`
+	Category  string    `json:"category"`
+	Detail    string    `json:"detail,omitempty"`
+	ChangedBy string    `json:"changedBy"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type Doubt struct {
+	ID            int          `json:"id"`
+	StudentEmail  string       `json:"studentEmail"`
+	QuizName      string       `json:"quizName,omitempty"`
+	QuestionIndex *int         `json:"questionIndex,omitempty"`
+	Topic         string       `json:"topic,omitempty"`
+	Text          string       `json:"text"`
+	Status        string       `json:"status"`
+	CreatedAt     time.Time    `json:"createdAt"`
+	ReplyCount    int          `json:"replyCount"`
+	Replies       []DoubtReply `json:"replies,omitempty"`
+}
+
+type DoubtReply struct {
+	ID          int       `json:"id"`
+	DoubtID     int       `json:"doubtId"`
+	AuthorEmail string    `json:"authorEmail"`
+	Text        string    `json:"text"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// ✅ Doubt Statuses
+const (
+	DoubtStatusOpen     = "open"
+	DoubtStatusAnswered = "answered"
+	DoubtStatusResolved = "resolved"
+)
+
+// ✅ Notification Kinds (rows in notifications wait for the sender to deliver them)
+const NotificationDoubtReply = "doubt_reply"
+
+// QuizVersion is one saved question set of a quiz (questions omitted in listings)
+type QuizVersion struct {
+	Version       int       `json:"version"`
+	Category      string    `json:"category"`
+	Duration      int       `json:"duration"`
+	QuestionCount int       `json:"questionCount"`
+	UploadedBy    string    `json:"uploadedBy"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+type StudentNote struct {
+	ID           int       `json:"id"`
+	StudentEmail string    `json:"studentEmail"`
+	AuthorEmail  string    `json:"authorEmail"`
+	Text         string    `json:"text"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+type Ban struct {
+	ID        int        `json:"id"`
+	Email     string     `json:"email"`
+	Scope     string     `json:"scope"`
+	Reason    string     `json:"reason"`
+	BannedBy  string     `json:"bannedBy"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+// ✅ Quiz Statuses (new quizzes start as drafts via the column default)
+const (
+	QuizStatusDraft     = "draft"
+	QuizStatusPublished = "published"
+	QuizStatusArchived  = "archived"
+)
+
+// ✅ Content Change Types (GET /changes). Detail holds the new status, the
+// previous name or the previous category respectively
+const (
+	ContentChangeCreated         = "quiz_created"
+	ContentChangeUpdated         = "quiz_updated"
+	ContentChangeStatus          = "quiz_status_changed"
+	ContentChangeDeleted         = "quiz_deleted"
+	ContentChangeRenamed         = "quiz_renamed"
+	ContentChangeCategoryChanged = "quiz_category_changed"
+)
+
+// ✅ Ban Scopes
+const (
+	BanScopeUploads = "uploads"
+	BanScopeQuiz    = "quiz"
+	BanScopeFull    = "full"
+)
+
+// BulkDeleteRequest selects quizzes to soft-delete; without ConfirmToken only a
+// preview is returned
+type BulkDeleteRequest struct {
+	Category     string `json:"category"`
+	NamePattern  string `json:"namePattern"` // '*' matches any text, case-insensitive
+	CreatedFrom  string `json:"createdFrom"` // YYYY-MM-DD
+	CreatedTo    string `json:"createdTo"`   // YYYY-MM-DD, inclusive
+	ConfirmToken string `json:"confirmToken"`
+}
+
+// AssembleQuizRequest builds a new quiz from the question bank; each section
+// samples Count random questions of its topic/difficulty (blank matches any)
+type AssembleQuizRequest struct {
+	QuizName     string         `json:"quizName"`
+	Category     string         `json:"category"`
+	Duration     int            `json:"duration"`
+	Instructions string         `json:"instructions"`
+	Sections     []BankSelector `json:"sections"`
+}
+
+type BankSelector struct {
+	Topic      string `json:"topic"`
+	Difficulty string `json:"difficulty"`
+	Count      int    `json:"count"`
+}
+
+// BankTagUpdate is one row of a bulk-tag sheet; blank Topic/Difficulty leave
+// the stored value alone
+type BankTagUpdate struct {
+	Row         int
+	ID          int64
+	Hash        string
+	QuestionKey string // normalized question text, optionally within Category
+	Category    string
+	Topic       string
+	Difficulty  string
+}
+
+// Bulk-tag updates sent to Postgres per statement
+const bankTagChunkSize = 500
+
+// BankSummary is one topic/difficulty bucket of GET /admin/question-bank
+type BankSummary struct {
+	Topic      string `json:"topic"`
+	Difficulty string `json:"difficulty"`
+	Questions  int    `json:"questions"`
 }
 
 type StudentUpdateRequest struct {
@@ -109,16 +615,59 @@ type StudentUpdateRequest struct {
 
 // ✅ Connect to PostgreSQL
 func connectDB() (*sql.DB, error) {
+	host, port := activeDBHost()
 	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=require",
-		DBHost, DBPort, DBUser, DBPassword, DBName)
+		host, port, DBUser, DBPassword, DBName)
 	return sql.Open("postgres", dsn)
 }
 
+// ✅ Pool for the lookups done on every request (token email, bans), kept open
+// across warm invocations instead of connecting per request
+var (
+	sharedPool     *sql.DB
+	sharedPoolErr  error
+	sharedPoolOnce sync.Once
+)
+
+func sharedDB() (*sql.DB, error) {
+	sharedPoolOnce.Do(func() {
+		sharedPool, sharedPoolErr = connectDB()
+		if sharedPoolErr == nil {
+			sharedPool.SetMaxOpenConns(2)
+			sharedPool.SetConnMaxIdleTime(5 * time.Minute)
+		}
+	})
+	return sharedPool, sharedPoolErr
+}
+
+// ✅ Resolve which database the Lambda is bound to; a standby target without a
+// standby host stays on the primary
+func activeDBTarget() string {
+	if strings.EqualFold(DBFailoverTarget, DBTargetStandby) {
+		if DBStandbyHost != "" {
+			return DBTargetStandby
+		}
+		log.Println("⚠️ DB_FAILOVER_TARGET=standby but POSTGRESQL_STANDBY_HOST is not set, using primary")
+	}
+	return DBTargetPrimary
+}
+
+// Helper function to get the host and port of the active database
+func activeDBHost() (string, string) {
+	if activeDBTarget() == DBTargetStandby {
+		if DBStandbyPort != "" {
+			return DBStandbyHost, DBStandbyPort
+		}
+		return DBStandbyHost, DBPort
+	}
+	return DBHost, DBPort
+}
+
 // ✅ CORS Headers Helper Function
 func getCORSHeaders() map[string]string {
 	return map[string]string{
 		"Access-Control-Allow-Origin":  "*",
-		"Access-Control-Allow-Methods": "OPTIONS, POST, PUT",
+		"Access-Control-Allow-Methods": "OPTIONS, GET, POST, PUT, PATCH, DELETE",
 		"Access-Control-Allow-Headers": "Content-Type, Authorization",
 	}
 }
@@ -136,9 +685,10 @@ func lambdaHandler(request events.LambdaFunctionURLRequest) (events.LambdaFuncti
 		}, nil
 	}
 
-	// ✅ Skip token verification for student update (handled in specific handler)
+	// ✅ Skip token verification for student update (handled in specific handler,
+	// which also enforces bans)
 	if request.RawPath != "/students/update" {
-		_, err := verifyFirebaseToken(request)
+		token, err := verifyFirebaseToken(request)
 		if err != nil {
 			log.Printf("❌ Authorization error: %v", err)
 			return events.LambdaFunctionURLResponse{
@@ -147,14 +697,83 @@ func lambdaHandler(request events.LambdaFunctionURLRequest) (events.LambdaFuncti
 				Body:       fmt.Sprintf(`{"error": "Unauthorized", "message": "%s"}`, err.Error()),
 			}, nil
 		}
+
+		// ✅ Enforce soft bans before routing
+		db, err := sharedDB()
+		if err != nil {
+			log.Printf("⚠️ Failed to check bans: %v", err)
+		} else {
+			email, err := resolveTokenEmail(db, token)
+			if err != nil {
+				log.Printf("⚠️ Could not resolve user for ban check: %v", err)
+			}
+			if ban := findActiveBan(db, email, request.RawPath); ban != nil {
+				return bannedResponse(*ban), nil
+			}
+		}
 	}
 
 	// ✅ Route API Requests
-	switch request.RawPath {
-	case "/upload/questions":
+	path := request.RawPath
+	switch {
+	case path == "/upload/questions":
 		return handleQuizUpload(request)
-	case "/students/update":
+	case path == "/upload/history":
+		return handleUploadHistory(request)
+	case path == "/upload/questions/template":
+		return handleTemplateDownload(request)
+	case path == "/students/update":
 		return handleStudentUpdate(request)
+	case path == "/quizzes":
+		return handleQuizzes(request)
+	case path == "/quizzes/rename":
+		return handleRenameQuiz(request)
+	case path == "/quizzes/versions":
+		return handleQuizVersions(request)
+	case path == "/quizzes/rollback":
+		return handleQuizRollback(request)
+	case path == "/quizzes/window":
+		return handleQuizWindow(request)
+	case path == "/quizzes/scoring":
+		return handleQuizScoring(request)
+	case path == "/quizzes/duplicate":
+		return handleDuplicateQuiz(request)
+	case path == "/quizzes/publish":
+		return handleQuizPublish(request)
+	case strings.HasPrefix(path, "/quizzes/") && strings.HasSuffix(path, "/info"):
+		return handleQuizInfo(request)
+	case path == "/changes":
+		return handleContentChanges(request)
+	case path == "/schedule":
+		return handleGetSchedule(request)
+	case path == "/admin/schedule":
+		return handleAdminSchedule(request)
+	case path == "/doubts":
+		return handleDoubts(request)
+	case path == "/doubts/reply":
+		return handleDoubtReply(request)
+	case path == "/doubts/resolve":
+		return handleDoubtResolve(request)
+	case path == "/admin/failover-status":
+		return handleFailoverStatus(request)
+	case path == "/admin/bans":
+		return handleAdminBans(request)
+	case path == "/admin/students/notes":
+		return handleStudentNotes(request)
+	case path == "/admin/students/sync-verification":
+		return handleSyncEmailVerification(request)
+	case path == "/admin/question-bank":
+		return handleQuestionBankSummary(request)
+	case path == "/admin/question-bank/assemble":
+		return handleAssembleQuiz(request)
+	case path == "/admin/questions/bulk-tag":
+		return handleBulkTagQuestions(request)
+	case path == "/admin/content-calendar":
+		return handleContentCalendar(request)
+	case path == "/admin/quizzes/bulk-delete":
+		return handleBulkDeleteQuizzes(request)
+	case strings.HasPrefix(path, "/admin/quizzes/") && strings.HasSuffix(path, "/preview"):
+		return handleQuizPreview(request)
 	default:
 		log.Printf("❌ Invalid API Path: %s", request.RawPath)
 		return events.LambdaFunctionURLResponse{
@@ -178,6 +797,98 @@ func getUserRole(db *sql.DB, email string) (string, error) {
 	return role.String, nil
 }
 
+// ✅ Verify Firebase Token and Extract Email
+func getAuthenticatedEmail(request events.LambdaFunctionURLRequest) (string, error) {
+	token, err := verifyFirebaseToken(request)
+	if err != nil {
+		return "", err
+	}
+	db, err := sharedDB()
+	if err != nil {
+		return "", err
+	}
+	return resolveTokenEmail(db, token)
+}
+
+// ✅ Phone-auth sign-in (PHONE_AUTH_ENABLED=true): tokens without an email
+// claim are resolved to a student by uid or phone number
+var PhoneAuthEnabled = os.Getenv("PHONE_AUTH_ENABLED") == "true"
+
+// ✅ Get the student email behind a verified token. Phone-auth users are found
+// by their linked uid, else by phone number (last 10 digits, so numbers stored
+// without a country code still match), and their uid is linked on first use.
+func resolveTokenEmail(db *sql.DB, token *auth.Token) (string, error) {
+	if email, _ := token.Claims["email"].(string); email != "" {
+		return email, nil
+	}
+	phoneNumber, _ := token.Claims["phone_number"].(string)
+	if !PhoneAuthEnabled || phoneNumber == "" {
+		return "", fmt.Errorf("token has no email claim")
+	}
+
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, phoneNumber)
+	if len(digits) < 10 {
+		return "", fmt.Errorf("invalid phone number claim")
+	}
+
+	var email string
+	err := db.QueryRow("SELECT email FROM students WHERE firebase_uid = $1", token.UID).Scan(&email)
+	if err == nil {
+		return email, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return "", err
+	}
+
+	rows, err := db.Query(`
+		SELECT email FROM students
+		WHERE firebase_uid IS NULL AND RIGHT(regexp_replace(phone_number, '\D', '', 'g'), 10) = $1
+		LIMIT 2`, digits[len(digits)-10:])
+	if err != nil {
+		return "", err
+	}
+	var matches []string
+	for rows.Next() {
+		if err := rows.Scan(&email); err != nil {
+			rows.Close()
+			return "", err
+		}
+		matches = append(matches, email)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	if len(matches) == 0 {
+		return "", fmt.Errorf("no student registered with phone number %s", phoneNumber)
+	}
+	if len(matches) > 1 {
+		return "", fmt.Errorf("phone number %s matches several students", phoneNumber)
+	}
+
+	_, err = db.Exec("UPDATE students SET firebase_uid = $1 WHERE LOWER(email) = LOWER($2) AND firebase_uid IS NULL", token.UID, matches[0])
+	if err != nil {
+		return "", fmt.Errorf("failed to link uid: %w", err)
+	}
+	log.Printf("🔗 Linked uid %s to %s by phone number", token.UID, matches[0])
+	return matches[0], nil
+}
+
+// Helper function to check a role against the allowed list
+func hasRole(role string, allowedRoles ...string) bool {
+	for _, allowed := range allowedRoles {
+		if role == allowed {
+			return true
+		}
+	}
+	return false
+}
+
 // ✅ Handle Student Update
 func handleStudentUpdate(request events.LambdaFunctionURLRequest) (events.LambdaFunctionURLResponse, error) {
 	// ✅ Verify Firebase Token and Extract Email
@@ -187,8 +898,23 @@ func handleStudentUpdate(request events.LambdaFunctionURLRequest) (events.Lambda
 		return createErrorResponse(401, "Unauthorized"), nil
 	}
 
-	userEmail := token.Claims["email"].(string)
+	// ✅ Connect to Database
+	db, err := connectDB()
+	if err != nil {
+		log.Println("❌ Database connection error:", err)
+		return createErrorResponse(500, "Database connection failed"), nil
+	}
+	defer db.Close()
+
+	userEmail, err := resolveTokenEmail(db, token)
+	if err != nil {
+		log.Printf("❌ Token verification failed: %v", err)
+		return createErrorResponse(401, "Unauthorized"), nil
+	}
 	log.Printf("🔐 Authenticated user: %s", userEmail)
+	if ban := findActiveBan(db, userEmail, request.RawPath); ban != nil {
+		return bannedResponse(*ban), nil
+	}
 
 	var studentUpdate StudentUpdateRequest
 	err = json.Unmarshal([]byte(request.Body), &studentUpdate)
@@ -202,14 +928,6 @@ func handleStudentUpdate(request events.LambdaFunctionURLRequest) (events.Lambda
 		return createErrorResponse(400, "Missing 'email' parameter"), nil
 	}
 
-	// ✅ Connect to Database
-	db, err := connectDB()
-	if err != nil {
-		log.Println("❌ Database connection error:", err)
-		return createErrorResponse(500, "Database connection failed"), nil
-	}
-	defer db.Close()
-
 	// ✅ Get User Role
 	userRole, err := getUserRole(db, userEmail)
 	if err != nil {
@@ -372,81 +1090,4790 @@ func updateStudent(db *sql.DB, student StudentUpdateRequest) (int64, error) {
 	return rowsAffected, nil
 }
 
-// ✅ Handle Quiz Upload
-func handleQuizUpload(request events.LambdaFunctionURLRequest) (events.LambdaFunctionURLResponse, error) {
-	queryParams := request.QueryStringParameters
-	category := queryParams["category"]
-	durationStr := queryParams["duration"]
-	quizName := queryParams["quizName"]
-
-	if category == "" || durationStr == "" || quizName == "" {
-		return createErrorResponse(400, "Missing required query parameters"), nil
+// ✅ Handle Student Schedule (upcoming exams for the caller's class)
+func handleGetSchedule(request events.LambdaFunctionURLRequest) (events.LambdaFunctionURLResponse, error) {
+	if request.RequestContext.HTTP.Method != "GET" {
+		return createErrorResponse(405, "Method not allowed"), nil
 	}
 
-	duration, err := strconv.Atoi(durationStr)
+	userEmail, err := getAuthenticatedEmail(request)
 	if err != nil {
-		return createErrorResponse(400, "Invalid duration format"), nil
+		log.Printf("❌ Token verification failed: %v", err)
+		return createErrorResponse(401, "Unauthorized"), nil
 	}
 
-	fileContent, err := base64.StdEncoding.DecodeString(request.Body)
+	db, err := connectDB()
 	if err != nil {
-		return createErrorResponse(400, "Invalid file encoding"), nil
+		log.Println("❌ Database connection error:", err)
+		return createErrorResponse(500, "Database connection failed"), nil
 	}
+	defer db.Close()
 
-	quizData, err := processExcel(fileContent, category, duration, quizName)
+	var studentClass sql.NullString
+	err = db.QueryRow("SELECT student_class FROM students WHERE LOWER(email) = LOWER($1)", userEmail).Scan(&studentClass)
+	if errors.Is(err, sql.ErrNoRows) {
+		return createErrorResponse(404, "Student not found"), nil
+	}
 	if err != nil {
-		return createErrorResponse(500, "Failed to process Excel file"), nil
+		log.Printf("❌ Failed to fetch class for %s: %v", userEmail, err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+	if !studentClass.Valid || studentClass.String == "" {
+		return createJSONResponse(200, []ExamScheduleEntry{}), nil
 	}
 
-	err = saveToPostgres(quizData)
+	// ✅ Categories are the class or prefixed with it and a dash (CLS10 →
+	// CLS10-MATH), so CLS1 never picks up CLS10's exams
+	entries, err := queryExamSchedule(db, `
+		SELECT id, exam_date, category, description FROM exam_schedule
+		WHERE exam_date >= CURRENT_DATE AND (category = $1 OR category LIKE $2 ESCAPE '\')
+		ORDER BY exam_date, category`, studentClass.String, classCategoryPattern(studentClass.String))
 	if err != nil {
-		return createErrorResponse(500, "Failed to save to database"), nil
+		log.Printf("❌ Failed to fetch schedule: %v", err)
+		return createErrorResponse(500, "Internal server error"), nil
 	}
+	return createJSONResponse(200, entries), nil
+}
 
-	return createSuccessResponse("Quiz uploaded successfully"), nil
+// Helper function to build the LIKE pattern matching a class's categories
+func classCategoryPattern(studentClass string) string {
+	return escapeLike(studentClass) + "-%"
 }
 
-func processExcel(fileBytes []byte, category string, duration int, quizName string) (QuizData, error) {
-	f, err := excelize.OpenReader(bytes.NewReader(fileBytes))
+// ✅ Handle Admin Schedule CRUD (GET list, POST create, PUT update, DELETE ?id=)
+func handleAdminSchedule(request events.LambdaFunctionURLRequest) (events.LambdaFunctionURLResponse, error) {
+	userEmail, err := getAuthenticatedEmail(request)
 	if err != nil {
-		return QuizData{}, err
+		log.Printf("❌ Token verification failed: %v", err)
+		return createErrorResponse(401, "Unauthorized"), nil
 	}
 
-	sheetName := f.GetSheetName(0)
-	rows, err := f.GetRows(sheetName)
+	db, err := connectDB()
 	if err != nil {
-		return QuizData{}, err
+		log.Println("❌ Database connection error:", err)
+		return createErrorResponse(500, "Database connection failed"), nil
 	}
+	defer db.Close()
 
-	if len(rows) < 2 {
-		return QuizData{}, errors.New("insufficient data in the file")
+	userRole, err := getUserRole(db, userEmail)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("❌ Failed to get user role: %v", err)
+		return createErrorResponse(500, "Failed to verify user permissions"), nil
 	}
-
-	// Read headers from the first row
-	headerMap := make(map[string]int)
-	for i, header := range rows[0] {
-		headerMap[header] = i
+	if !hasRole(userRole, "admin", "super") {
+		return createErrorResponse(403, "Only 'admin' or 'super' role can manage the exam schedule"), nil
 	}
 
-	// Required headers
-	requiredHeaders := []string{"Question", "CorrectAnswer", "IncorrectAnswers", "Explanation"}
-	for _, header := range requiredHeaders {
-		if _, exists := headerMap[header]; !exists {
-			return QuizData{}, fmt.Errorf("missing required column: %s", header)
+	switch request.RequestContext.HTTP.Method {
+	case "GET":
+		entries, err := queryExamSchedule(db, `
+			SELECT id, exam_date, category, description FROM exam_schedule
+			WHERE exam_date >= CURRENT_DATE ORDER BY exam_date, category`)
+		if err != nil {
+			log.Printf("❌ Failed to fetch schedule: %v", err)
+			return createErrorResponse(500, "Internal server error"), nil
 		}
-	}
+		return createJSONResponse(200, entries), nil
 
-	var questions []Question
-	for _, row := range rows[1:] {
-		questions = append(questions, Question{
-			Question:         getCellValue(row, headerMap, "Question"),
-			CorrectAnswer:    getCellValue(row, headerMap, "CorrectAnswer"),
-			IncorrectAnswers: getCellValue(row, headerMap, "IncorrectAnswers"),
-			Explanation:      getCellValue(row, headerMap, "Explanation"),
-		})
+	case "POST", "PUT":
+		var entry ExamScheduleEntry
+		if err := json.Unmarshal([]byte(request.Body), &entry); err != nil {
+			log.Println("❌ Error parsing JSON:", err)
+			return createErrorResponse(400, "Invalid JSON format"), nil
+		}
+		if _, err := time.Parse("2006-01-02", entry.Date); err != nil {
+			return createErrorResponse(400, "Invalid 'date', expected YYYY-MM-DD"), nil
+		}
+		if entry.Category == "" {
+			return createErrorResponse(400, "Missing 'category' parameter"), nil
+		}
+
+		if request.RequestContext.HTTP.Method == "POST" {
+			err = db.QueryRow(`
+				INSERT INTO exam_schedule (exam_date, category, description, created_by)
+				VALUES ($1, $2, $3, $4) RETURNING id`,
+				entry.Date, entry.Category, entry.Description, strings.ToLower(userEmail)).Scan(&entry.ID)
+			if err != nil {
+				log.Printf("❌ Failed to create schedule entry: %v", err)
+				return createErrorResponse(500, "Internal server error"), nil
+			}
+			return createJSONResponse(200, entry), nil
+		}
+
+		if entry.ID == 0 {
+			return createErrorResponse(400, "Missing 'id' parameter"), nil
+		}
+		result, err := db.Exec(`
+			UPDATE exam_schedule SET exam_date = $2, category = $3, description = $4
+			WHERE id = $1`, entry.ID, entry.Date, entry.Category, entry.Description)
+		if err != nil {
+			log.Printf("❌ Failed to update schedule entry %d: %v", entry.ID, err)
+			return createErrorResponse(500, "Internal server error"), nil
+		}
+		if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+			return createErrorResponse(404, "Schedule entry not found"), nil
+		}
+		return createJSONResponse(200, entry), nil
+
+	case "DELETE":
+		id, err := strconv.Atoi(request.QueryStringParameters["id"])
+		if err != nil {
+			return createErrorResponse(400, "Invalid 'id' parameter"), nil
+		}
+		result, err := db.Exec("DELETE FROM exam_schedule WHERE id = $1", id)
+		if err != nil {
+			log.Printf("❌ Failed to delete schedule entry %d: %v", id, err)
+			return createErrorResponse(500, "Internal server error"), nil
+		}
+		if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+			return createErrorResponse(404, "Schedule entry not found"), nil
+		}
+		return createSuccessResponse("Schedule entry deleted successfully"), nil
+
+	default:
+		return createErrorResponse(405, "Method not allowed"), nil
+	}
+}
+
+// ✅ Run a schedule query and scan the rows
+func queryExamSchedule(db *sql.DB, query string, args ...interface{}) ([]ExamScheduleEntry, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []ExamScheduleEntry{}
+	for rows.Next() {
+		var entry ExamScheduleEntry
+		var examDate time.Time
+		if err := rows.Scan(&entry.ID, &examDate, &entry.Category, &entry.Description); err != nil {
+			return nil, err
+		}
+		entry.Date = examDate.Format("2006-01-02")
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// ✅ Handle Doubts (GET list / single with ?id=, POST create)
+func handleDoubts(request events.LambdaFunctionURLRequest) (events.LambdaFunctionURLResponse, error) {
+	userEmail, err := getAuthenticatedEmail(request)
+	if err != nil {
+		log.Printf("❌ Token verification failed: %v", err)
+		return createErrorResponse(401, "Unauthorized"), nil
+	}
+	userEmail = strings.ToLower(userEmail)
+
+	db, err := connectDB()
+	if err != nil {
+		log.Println("❌ Database connection error:", err)
+		return createErrorResponse(500, "Database connection failed"), nil
+	}
+	defer db.Close()
+
+	switch request.RequestContext.HTTP.Method {
+	case "POST":
+		var doubt Doubt
+		if err := json.Unmarshal([]byte(request.Body), &doubt); err != nil {
+			log.Println("❌ Error parsing JSON:", err)
+			return createErrorResponse(400, "Invalid JSON format"), nil
+		}
+		if strings.TrimSpace(doubt.Text) == "" {
+			return createErrorResponse(400, "Missing 'text' parameter"), nil
+		}
+		if doubt.QuizName == "" && doubt.Topic == "" {
+			return createErrorResponse(400, "A doubt must reference a 'quizName' or a 'topic'"), nil
+		}
+
+		doubt.StudentEmail = userEmail
+		doubt.Status = DoubtStatusOpen
+		err = db.QueryRow(`
+			INSERT INTO doubts (student_email, quiz_name, question_index, topic, body, status)
+			VALUES ($1, NULLIF($2, ''), $3, NULLIF($4, ''), $5, $6)
+			RETURNING id, created_at`,
+			doubt.StudentEmail, doubt.QuizName, doubt.QuestionIndex, doubt.Topic, doubt.Text, doubt.Status).
+			Scan(&doubt.ID, &doubt.CreatedAt)
+		if err != nil {
+			log.Printf("❌ Failed to create doubt for %s: %v", userEmail, err)
+			return createErrorResponse(500, "Internal server error"), nil
+		}
+		return createJSONResponse(200, doubt), nil
+
+	case "GET":
+		userRole, err := getUserRole(db, userEmail)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("❌ Failed to get user role: %v", err)
+			return createErrorResponse(500, "Failed to verify user permissions"), nil
+		}
+		isStaff := hasRole(userRole, "teacher", "admin", "super")
+
+		// ✅ Students only ever see their own doubts
+		query := `
+			SELECT d.id, d.student_email, COALESCE(d.quiz_name, ''), d.question_index, COALESCE(d.topic, ''),
+				d.body, d.status, d.created_at, (SELECT COUNT(*) FROM doubt_replies r WHERE r.doubt_id = d.id)
+			FROM doubts d WHERE ($1 OR d.student_email = $2)`
+		params := []interface{}{isStaff, userEmail}
+		if idStr := request.QueryStringParameters["id"]; idStr != "" {
+			id, err := strconv.Atoi(idStr)
+			if err != nil {
+				return createErrorResponse(400, "Invalid 'id' parameter"), nil
+			}
+			params = append(params, id)
+			query += fmt.Sprintf(" AND d.id = $%d", len(params))
+		}
+		if status := request.QueryStringParameters["status"]; status != "" {
+			params = append(params, status)
+			query += fmt.Sprintf(" AND d.status = $%d", len(params))
+		}
+		if quizName := request.QueryStringParameters["quizName"]; quizName != "" {
+			params = append(params, quizName)
+			query += fmt.Sprintf(" AND d.quiz_name = $%d", len(params))
+		}
+		query += " ORDER BY d.created_at DESC LIMIT 200"
+
+		doubts, err := queryDoubts(db, query, params...)
+		if err != nil {
+			log.Printf("❌ Failed to list doubts: %v", err)
+			return createErrorResponse(500, "Internal server error"), nil
+		}
+
+		if request.QueryStringParameters["id"] == "" {
+			return createJSONResponse(200, doubts), nil
+		}
+		if len(doubts) == 0 {
+			return createErrorResponse(404, "Doubt not found"), nil
+		}
+		doubts[0].Replies, err = queryDoubtReplies(db, doubts[0].ID)
+		if err != nil {
+			log.Printf("❌ Failed to fetch replies for doubt %d: %v", doubts[0].ID, err)
+			return createErrorResponse(500, "Internal server error"), nil
+		}
+		return createJSONResponse(200, doubts[0]), nil
+
+	default:
+		return createErrorResponse(405, "Method not allowed"), nil
+	}
+}
+
+// ✅ Handle Doubt Reply (teachers/admins answer a doubt)
+func handleDoubtReply(request events.LambdaFunctionURLRequest) (events.LambdaFunctionURLResponse, error) {
+	if request.RequestContext.HTTP.Method != "POST" {
+		return createErrorResponse(405, "Method not allowed"), nil
+	}
+
+	userEmail, err := getAuthenticatedEmail(request)
+	if err != nil {
+		log.Printf("❌ Token verification failed: %v", err)
+		return createErrorResponse(401, "Unauthorized"), nil
+	}
+
+	var reply DoubtReply
+	if err := json.Unmarshal([]byte(request.Body), &reply); err != nil {
+		log.Println("❌ Error parsing JSON:", err)
+		return createErrorResponse(400, "Invalid JSON format"), nil
+	}
+	if reply.DoubtID == 0 || strings.TrimSpace(reply.Text) == "" {
+		return createErrorResponse(400, "Missing 'doubtId' or 'text' parameter"), nil
+	}
+
+	db, err := connectDB()
+	if err != nil {
+		log.Println("❌ Database connection error:", err)
+		return createErrorResponse(500, "Database connection failed"), nil
+	}
+	defer db.Close()
+
+	userRole, err := getUserRole(db, userEmail)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("❌ Failed to get user role: %v", err)
+		return createErrorResponse(500, "Failed to verify user permissions"), nil
+	}
+	if !hasRole(userRole, "teacher", "admin", "super") {
+		return createErrorResponse(403, "Only 'teacher', 'admin' or 'super' role can reply to doubts"), nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Printf("❌ Failed to begin transaction: %v", err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("UPDATE doubts SET status = $2 WHERE id = $1 AND status <> $3",
+		reply.DoubtID, DoubtStatusAnswered, DoubtStatusResolved)
+	if err != nil {
+		log.Printf("❌ Failed to update doubt %d: %v", reply.DoubtID, err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return createErrorResponse(404, "No open doubt found with the provided id"), nil
+	}
+
+	reply.AuthorEmail = strings.ToLower(userEmail)
+	err = tx.QueryRow(`
+		INSERT INTO doubt_replies (doubt_id, author_email, body)
+		VALUES ($1, $2, $3) RETURNING id, created_at`,
+		reply.DoubtID, reply.AuthorEmail, reply.Text).Scan(&reply.ID, &reply.CreatedAt)
+	if err != nil {
+		log.Printf("❌ Failed to save reply for doubt %d: %v", reply.DoubtID, err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+
+	// ✅ Queue a notification to the student who asked
+	_, err = tx.Exec(`
+		INSERT INTO notifications (recipient_email, kind, doubt_id, body)
+		SELECT student_email, $2, id, $3 FROM doubts WHERE id = $1`,
+		reply.DoubtID, NotificationDoubtReply, reply.Text)
+	if err != nil {
+		log.Printf("❌ Failed to queue notification for doubt %d: %v", reply.DoubtID, err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("❌ Failed to commit reply for doubt %d: %v", reply.DoubtID, err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+	return createJSONResponse(200, reply), nil
+}
+
+// ✅ Handle Doubt Resolve (the student who asked, or staff)
+func handleDoubtResolve(request events.LambdaFunctionURLRequest) (events.LambdaFunctionURLResponse, error) {
+	if request.RequestContext.HTTP.Method != "POST" {
+		return createErrorResponse(405, "Method not allowed"), nil
+	}
+
+	userEmail, err := getAuthenticatedEmail(request)
+	if err != nil {
+		log.Printf("❌ Token verification failed: %v", err)
+		return createErrorResponse(401, "Unauthorized"), nil
+	}
+	userEmail = strings.ToLower(userEmail)
+
+	var body struct {
+		DoubtID int `json:"doubtId"`
+	}
+	if err := json.Unmarshal([]byte(request.Body), &body); err != nil || body.DoubtID == 0 {
+		return createErrorResponse(400, "Missing 'doubtId' parameter"), nil
+	}
+
+	db, err := connectDB()
+	if err != nil {
+		log.Println("❌ Database connection error:", err)
+		return createErrorResponse(500, "Database connection failed"), nil
+	}
+	defer db.Close()
+
+	userRole, err := getUserRole(db, userEmail)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("❌ Failed to get user role: %v", err)
+		return createErrorResponse(500, "Failed to verify user permissions"), nil
+	}
+
+	result, err := db.Exec(`
+		UPDATE doubts SET status = $2, resolved_at = NOW()
+		WHERE id = $1 AND ($3 OR student_email = $4)`,
+		body.DoubtID, DoubtStatusResolved, hasRole(userRole, "teacher", "admin", "super"), userEmail)
+	if err != nil {
+		log.Printf("❌ Failed to resolve doubt %d: %v", body.DoubtID, err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return createErrorResponse(404, "Doubt not found"), nil
+	}
+	return createSuccessResponse("Doubt resolved successfully"), nil
+}
+
+// ✅ Run a doubts query and scan the rows
+func queryDoubts(db *sql.DB, query string, args ...interface{}) ([]Doubt, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	doubts := []Doubt{}
+	for rows.Next() {
+		var doubt Doubt
+		var questionIndex sql.NullInt64
+		err := rows.Scan(&doubt.ID, &doubt.StudentEmail, &doubt.QuizName, &questionIndex, &doubt.Topic,
+			&doubt.Text, &doubt.Status, &doubt.CreatedAt, &doubt.ReplyCount)
+		if err != nil {
+			return nil, err
+		}
+		if questionIndex.Valid {
+			index := int(questionIndex.Int64)
+			doubt.QuestionIndex = &index
+		}
+		doubts = append(doubts, doubt)
+	}
+	return doubts, rows.Err()
+}
+
+// ✅ Fetch the replies of a doubt, oldest first
+func queryDoubtReplies(db *sql.DB, doubtID int) ([]DoubtReply, error) {
+	rows, err := db.Query(`
+		SELECT id, doubt_id, author_email, body, created_at FROM doubt_replies
+		WHERE doubt_id = $1 ORDER BY created_at`, doubtID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	replies := []DoubtReply{}
+	for rows.Next() {
+		var reply DoubtReply
+		if err := rows.Scan(&reply.ID, &reply.DoubtID, &reply.AuthorEmail, &reply.Text, &reply.CreatedAt); err != nil {
+			return nil, err
+		}
+		replies = append(replies, reply)
+	}
+	return replies, rows.Err()
+}
+
+// ✅ Ban scope guarding a path; full bans block every path as well. Quiz bans
+// cover quizzes and the study features around them (doubts, schedule)
+func banScopeForPath(path string) string {
+	switch {
+	case matchesPath(path, "/upload"):
+		return BanScopeUploads
+	case matchesPath(path, "/quizzes"), matchesPath(path, "/doubts"), matchesPath(path, "/schedule"):
+		return BanScopeQuiz
+	default:
+		return BanScopeFull
+	}
+}
+
+// Helper function to match a path or anything below it ("/quizzes", "/quizzes/x")
+func matchesPath(path, prefix string) bool {
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+// ✅ Find an active ban blocking this user on this path. Lookup failures are
+// logged and let the request through so a DB hiccup doesn't lock everyone out
+func findActiveBan(db *sql.DB, email, path string) *Ban {
+	if email == "" {
+		return nil
+	}
+
+	var ban Ban
+	err := db.QueryRow(`
+		SELECT id, email, scope, reason, banned_by, expires_at, created_at FROM bans
+		WHERE email = $1 AND scope IN ($2, $3) AND lifted_at IS NULL
+			AND (expires_at IS NULL OR expires_at > NOW())
+		ORDER BY created_at DESC LIMIT 1`, strings.ToLower(email), BanScopeFull, banScopeForPath(path)).
+		Scan(&ban.ID, &ban.Email, &ban.Scope, &ban.Reason, &ban.BannedBy, &ban.ExpiresAt, &ban.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		log.Printf("⚠️ Failed to check bans: %v", err)
+		return nil
+	}
+	log.Printf("⛔ Blocked %s on %s by ban %d (%s)", ban.Email, path, ban.ID, ban.Scope)
+	return &ban
+}
+
+// ✅ 403 with a dedicated code so clients can tell bans from missing roles
+func bannedResponse(ban Ban) events.LambdaFunctionURLResponse {
+	return createJSONResponse(403, map[string]interface{}{
+		"error":     "Account restricted",
+		"code":      "ACCOUNT_BANNED",
+		"scope":     ban.Scope,
+		"reason":    ban.Reason,
+		"expiresAt": ban.ExpiresAt,
+	})
+}
+
+// ✅ Handle Email Verification Sync (admin/super): copies Firebase's
+// email_verified flag onto every student and reports unverified paid accounts
+func handleSyncEmailVerification(request events.LambdaFunctionURLRequest) (events.LambdaFunctionURLResponse, error) {
+	if request.RequestContext.HTTP.Method != "POST" {
+		return createErrorResponse(405, "Method not allowed"), nil
+	}
+
+	userEmail, err := getAuthenticatedEmail(request)
+	if err != nil {
+		log.Printf("❌ Token verification failed: %v", err)
+		return createErrorResponse(401, "Unauthorized"), nil
+	}
+
+	db, err := connectDB()
+	if err != nil {
+		log.Println("❌ Database connection error:", err)
+		return createErrorResponse(500, "Database connection failed"), nil
+	}
+	defer db.Close()
+
+	userRole, err := getUserRole(db, userEmail)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("❌ Failed to get user role: %v", err)
+		return createErrorResponse(500, "Failed to verify user permissions"), nil
+	}
+	if !hasRole(userRole, "admin", "super") {
+		return createErrorResponse(403, "Only 'admin' or 'super' role can sync verification status"), nil
+	}
+
+	// ✅ Page through every Firebase user
+	var emails []string
+	var verified []bool
+	users := firebaseAuth.Users(context.Background(), "")
+	for {
+		user, err := users.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			log.Printf("❌ Failed to list Firebase users: %v", err)
+			return createErrorResponse(502, "Failed to list Firebase users"), nil
+		}
+		if user.Email == "" {
+			continue
+		}
+		emails = append(emails, strings.ToLower(user.Email))
+		verified = append(verified, user.EmailVerified)
+	}
+
+	result, err := db.Exec(`
+		UPDATE students s SET email_verified = v.verified, email_verified_synced_at = NOW()
+		FROM unnest($1::text[], $2::boolean[]) AS v(email, verified)
+		WHERE LOWER(s.email) = v.email`, pq.Array(emails), pq.Array(verified))
+	if err != nil {
+		log.Printf("❌ Failed to update verification status: %v", err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+	updated, _ := result.RowsAffected()
+
+	rows, err := db.Query("SELECT email FROM students WHERE email_verified = false AND amount > 0 ORDER BY email")
+	if err != nil {
+		log.Printf("❌ Failed to fetch unverified paid students: %v", err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+	defer rows.Close()
+
+	unverifiedPaid := []string{}
+	for rows.Next() {
+		var email string
+		if err := rows.Scan(&email); err != nil {
+			log.Printf("❌ Failed to read unverified paid students: %v", err)
+			return createErrorResponse(500, "Internal server error"), nil
+		}
+		unverifiedPaid = append(unverifiedPaid, email)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("❌ Failed to read unverified paid students: %v", err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+
+	log.Printf("✅ Synced email verification for %d students (%d Firebase users)", updated, len(emails))
+	return createJSONResponse(200, map[string]interface{}{
+		"firebaseUsers":  len(emails),
+		"studentsSynced": updated,
+		"unverifiedPaid": unverifiedPaid,
+	}), nil
+}
+
+// ✅ Handle Student Notes (admin/super): GET ?email= lists a student's notes
+// newest first, POST appends one. Notes are never edited or deleted.
+func handleStudentNotes(request events.LambdaFunctionURLRequest) (events.LambdaFunctionURLResponse, error) {
+	userEmail, err := getAuthenticatedEmail(request)
+	if err != nil {
+		log.Printf("❌ Token verification failed: %v", err)
+		return createErrorResponse(401, "Unauthorized"), nil
+	}
+	userEmail = strings.ToLower(userEmail)
+
+	db, err := connectDB()
+	if err != nil {
+		log.Println("❌ Database connection error:", err)
+		return createErrorResponse(500, "Database connection failed"), nil
+	}
+	defer db.Close()
+
+	userRole, err := getUserRole(db, userEmail)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("❌ Failed to get user role: %v", err)
+		return createErrorResponse(500, "Failed to verify user permissions"), nil
+	}
+	if !hasRole(userRole, "admin", "super") {
+		return createErrorResponse(403, "Only 'admin' or 'super' role can access student notes"), nil
+	}
+
+	switch request.RequestContext.HTTP.Method {
+	case "GET":
+		studentEmail := strings.ToLower(strings.TrimSpace(request.QueryStringParameters["email"]))
+		if studentEmail == "" {
+			return createErrorResponse(400, "Missing 'email' parameter"), nil
+		}
+
+		rows, err := db.Query(`
+			SELECT id, student_email, author_email, body, created_at FROM student_notes
+			WHERE student_email = $1 ORDER BY created_at DESC`, studentEmail)
+		if err != nil {
+			log.Printf("❌ Failed to fetch notes for %s: %v", studentEmail, err)
+			return createErrorResponse(500, "Internal server error"), nil
+		}
+		defer rows.Close()
+
+		notes := []StudentNote{}
+		for rows.Next() {
+			var note StudentNote
+			if err := rows.Scan(&note.ID, &note.StudentEmail, &note.AuthorEmail, &note.Text, &note.CreatedAt); err != nil {
+				log.Printf("❌ Failed to read notes: %v", err)
+				return createErrorResponse(500, "Internal server error"), nil
+			}
+			notes = append(notes, note)
+		}
+		if err := rows.Err(); err != nil {
+			log.Printf("❌ Failed to read notes: %v", err)
+			return createErrorResponse(500, "Internal server error"), nil
+		}
+		return createJSONResponse(200, notes), nil
+
+	case "POST":
+		var note StudentNote
+		if err := json.Unmarshal([]byte(request.Body), &note); err != nil {
+			log.Println("❌ Error parsing JSON:", err)
+			return createErrorResponse(400, "Invalid JSON format"), nil
+		}
+		note.StudentEmail = strings.ToLower(strings.TrimSpace(note.StudentEmail))
+		note.Text = strings.TrimSpace(note.Text)
+		if note.StudentEmail == "" || note.Text == "" {
+			return createErrorResponse(400, "Missing 'studentEmail' or 'text' parameter"), nil
+		}
+
+		var exists bool
+		if err := db.QueryRow("SELECT EXISTS (SELECT 1 FROM students WHERE LOWER(email) = LOWER($1))", note.StudentEmail).Scan(&exists); err != nil {
+			log.Printf("❌ Failed to look up student %s: %v", note.StudentEmail, err)
+			return createErrorResponse(500, "Internal server error"), nil
+		}
+		if !exists {
+			return createErrorResponse(404, "Student not found"), nil
+		}
+
+		note.AuthorEmail = userEmail
+		err = db.QueryRow(`
+			INSERT INTO student_notes (student_email, author_email, body)
+			VALUES ($1, $2, $3) RETURNING id, created_at`,
+			note.StudentEmail, note.AuthorEmail, note.Text).Scan(&note.ID, &note.CreatedAt)
+		if err != nil {
+			log.Printf("❌ Failed to add note for %s: %v", note.StudentEmail, err)
+			return createErrorResponse(500, "Internal server error"), nil
+		}
+		return createJSONResponse(200, note), nil
+
+	default:
+		return createErrorResponse(405, "Method not allowed"), nil
+	}
+}
+
+// ✅ Handle Bans (admin/super): GET lists active bans, POST bans, DELETE ?id= lifts a ban
+func handleAdminBans(request events.LambdaFunctionURLRequest) (events.LambdaFunctionURLResponse, error) {
+	userEmail, err := getAuthenticatedEmail(request)
+	if err != nil {
+		log.Printf("❌ Token verification failed: %v", err)
+		return createErrorResponse(401, "Unauthorized"), nil
+	}
+	userEmail = strings.ToLower(userEmail)
+
+	db, err := connectDB()
+	if err != nil {
+		log.Println("❌ Database connection error:", err)
+		return createErrorResponse(500, "Database connection failed"), nil
+	}
+	defer db.Close()
+
+	userRole, err := getUserRole(db, userEmail)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("❌ Failed to get user role: %v", err)
+		return createErrorResponse(500, "Failed to verify user permissions"), nil
+	}
+	if !hasRole(userRole, "admin", "super") {
+		return createErrorResponse(403, "Only 'admin' or 'super' role can manage bans"), nil
+	}
+
+	switch request.RequestContext.HTTP.Method {
+	case "GET":
+		query := `
+			SELECT id, email, scope, reason, banned_by, expires_at, created_at FROM bans
+			WHERE lifted_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())`
+		params := []interface{}{}
+		if email := request.QueryStringParameters["email"]; email != "" {
+			params = append(params, strings.ToLower(email))
+			query += " AND email = $1"
+		}
+		rows, err := db.Query(query+" ORDER BY created_at DESC", params...)
+		if err != nil {
+			log.Printf("❌ Failed to fetch bans: %v", err)
+			return createErrorResponse(500, "Internal server error"), nil
+		}
+		defer rows.Close()
+
+		bans := []Ban{}
+		for rows.Next() {
+			var ban Ban
+			if err := rows.Scan(&ban.ID, &ban.Email, &ban.Scope, &ban.Reason, &ban.BannedBy, &ban.ExpiresAt, &ban.CreatedAt); err != nil {
+				log.Printf("❌ Failed to read bans: %v", err)
+				return createErrorResponse(500, "Internal server error"), nil
+			}
+			bans = append(bans, ban)
+		}
+		if err := rows.Err(); err != nil {
+			log.Printf("❌ Failed to read bans: %v", err)
+			return createErrorResponse(500, "Internal server error"), nil
+		}
+		return createJSONResponse(200, bans), nil
+
+	case "POST":
+		var ban Ban
+		if err := json.Unmarshal([]byte(request.Body), &ban); err != nil {
+			log.Println("❌ Error parsing JSON:", err)
+			return createErrorResponse(400, "Invalid JSON format"), nil
+		}
+		ban.Email = strings.ToLower(strings.TrimSpace(ban.Email))
+		ban.Reason = strings.TrimSpace(ban.Reason)
+		if ban.Email == "" || ban.Reason == "" {
+			return createErrorResponse(400, "Missing 'email' or 'reason' parameter"), nil
+		}
+		if ban.Scope != BanScopeUploads && ban.Scope != BanScopeQuiz && ban.Scope != BanScopeFull {
+			return createErrorResponse(400, "scope must be 'uploads', 'quiz' or 'full'"), nil
+		}
+		if ban.ExpiresAt != nil && !ban.ExpiresAt.After(time.Now()) {
+			return createErrorResponse(400, "expiresAt must be in the future"), nil
+		}
+		if ban.Email == userEmail {
+			return createErrorResponse(400, "You cannot ban yourself"), nil
+		}
+
+		ban.BannedBy = userEmail
+		err = db.QueryRow(`
+			INSERT INTO bans (email, scope, reason, banned_by, expires_at)
+			VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at`,
+			ban.Email, ban.Scope, ban.Reason, ban.BannedBy, ban.ExpiresAt).Scan(&ban.ID, &ban.CreatedAt)
+		if err != nil {
+			log.Printf("❌ Failed to create ban for %s: %v", ban.Email, err)
+			return createErrorResponse(500, "Internal server error"), nil
+		}
+		log.Printf("⛔ %s banned %s (%s): %s", userEmail, ban.Email, ban.Scope, ban.Reason)
+		return createJSONResponse(200, ban), nil
+
+	case "DELETE":
+		id, err := strconv.Atoi(request.QueryStringParameters["id"])
+		if err != nil {
+			return createErrorResponse(400, "Invalid 'id' parameter"), nil
+		}
+		result, err := db.Exec(`
+			UPDATE bans SET lifted_at = NOW(), lifted_by = $2
+			WHERE id = $1 AND lifted_at IS NULL`, id, userEmail)
+		if err != nil {
+			log.Printf("❌ Failed to lift ban %d: %v", id, err)
+			return createErrorResponse(500, "Internal server error"), nil
+		}
+		if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+			return createErrorResponse(404, "Ban not found"), nil
+		}
+		return createSuccessResponse("Ban lifted successfully"), nil
+
+	default:
+		return createErrorResponse(405, "Method not allowed"), nil
+	}
+}
+
+// ✅ Handle Quizzes: GET lists quizzes, DELETE ?name= removes one
+func handleQuizzes(request events.LambdaFunctionURLRequest) (events.LambdaFunctionURLResponse, error) {
+	switch request.RequestContext.HTTP.Method {
+	case "GET":
+		return handleListQuizzes(request)
+	case "DELETE":
+		return handleDeleteQuiz(request)
+	default:
+		return createErrorResponse(405, "Method not allowed"), nil
+	}
+}
+
+// ✅ Handle Quiz List (teacher/admin/super, filters: category, prefix, status, tag,
+// topic, chapter, author, from, to, limit, offset)
+func handleListQuizzes(request events.LambdaFunctionURLRequest) (events.LambdaFunctionURLResponse, error) {
+	userEmail, err := getAuthenticatedEmail(request)
+	if err != nil {
+		log.Printf("❌ Token verification failed: %v", err)
+		return createErrorResponse(401, "Unauthorized"), nil
+	}
+
+	db, err := connectDB()
+	if err != nil {
+		log.Println("❌ Database connection error:", err)
+		return createErrorResponse(500, "Database connection failed"), nil
+	}
+	defer db.Close()
+
+	userRole, err := getUserRole(db, userEmail)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("❌ Failed to get user role: %v", err)
+		return createErrorResponse(500, "Failed to verify user permissions"), nil
+	}
+	if !hasRole(userRole, "teacher", "admin", "super") {
+		return createErrorResponse(403, "Only 'teacher', 'admin' or 'super' role can list quizzes"), nil
+	}
+
+	// ✅ Build Dynamic Filters
+	queryParams := request.QueryStringParameters
+	where := " WHERE deleted_at IS NULL"
+	params := []interface{}{}
+	if category := queryParams["category"]; category != "" {
+		params = append(params, category)
+		where += fmt.Sprintf(" AND category = $%d", len(params))
+	}
+	if prefix := queryParams["prefix"]; prefix != "" {
+		params = append(params, escapeLike(prefix)+"%")
+		where += fmt.Sprintf(" AND quiz_name ILIKE $%d", len(params))
+	}
+	if status := queryParams["status"]; status != "" {
+		params = append(params, status)
+		where += fmt.Sprintf(" AND status = $%d", len(params))
+	}
+	if tag := strings.TrimSpace(queryParams["tag"]); tag != "" {
+		params = append(params, strings.ToLower(tag))
+		where += fmt.Sprintf(" AND metadata->'tags' ? $%d", len(params))
+	}
+	for _, field := range []string{"topic", "chapter", "author"} {
+		if value := strings.TrimSpace(queryParams[field]); value != "" {
+			params = append(params, escapeLike(value))
+			where += fmt.Sprintf(" AND metadata->>'%s' ILIKE $%d", field, len(params))
+		}
+	}
+	for _, bound := range []struct{ param, condition string }{{"from", ">="}, {"to", "<"}} {
+		value := queryParams[bound.param]
+		if value == "" {
+			continue
+		}
+		date, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return createErrorResponse(400, fmt.Sprintf("Invalid '%s', expected YYYY-MM-DD", bound.param)), nil
+		}
+		if bound.param == "to" {
+			// Make the end date inclusive
+			date = date.AddDate(0, 0, 1)
+		}
+		params = append(params, date)
+		where += fmt.Sprintf(" AND created_at %s $%d", bound.condition, len(params))
+	}
+
+	limit, offset := 50, 0
+	if limitStr := queryParams["limit"]; limitStr != "" {
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 || limit > 500 {
+			return createErrorResponse(400, "limit must be between 1 and 500"), nil
+		}
+	}
+	if offsetStr := queryParams["offset"]; offsetStr != "" {
+		offset, err = strconv.Atoi(offsetStr)
+		if err != nil || offset < 0 {
+			return createErrorResponse(400, "offset must be a non-negative number"), nil
+		}
+	}
+
+	var total int
+	if err := db.QueryRow("SELECT COUNT(*) FROM quiz_questions"+where, params...).Scan(&total); err != nil {
+		log.Printf("❌ Failed to count quizzes: %v", err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+
+	query := `
+		SELECT quiz_name, category, duration, jsonb_array_length(questions), status, created_at, publish_at, close_at, metadata
+		FROM quiz_questions` + where +
+		fmt.Sprintf(" ORDER BY created_at DESC, quiz_name LIMIT $%d OFFSET $%d", len(params)+1, len(params)+2)
+	rows, err := db.Query(query, append(params, limit, offset)...)
+	if err != nil {
+		log.Printf("❌ Failed to fetch quizzes: %v", err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+	defer rows.Close()
+
+	quizzes := []QuizSummary{}
+	for rows.Next() {
+		var quiz QuizSummary
+		var metadataJSON []byte
+		err := rows.Scan(&quiz.QuizName, &quiz.Category, &quiz.Duration, &quiz.QuestionCount, &quiz.Status,
+			&quiz.CreatedAt, &quiz.PublishAt, &quiz.CloseAt, &metadataJSON)
+		if err == nil {
+			err = json.Unmarshal(metadataJSON, &quiz.Metadata)
+		}
+		if err != nil {
+			log.Printf("❌ Failed to read quizzes: %v", err)
+			return createErrorResponse(500, "Internal server error"), nil
+		}
+		quizzes = append(quizzes, quiz)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("❌ Failed to read quizzes: %v", err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+
+	return createJSONResponse(200, map[string]interface{}{
+		"quizzes": quizzes,
+		"total":   total,
+		"limit":   limit,
+		"offset":  offset,
+	}), nil
+}
+
+// ✅ Handle Quiz Delete (admin/super): soft-deletes the quiz and drops it from
+// every student's quiz list in one transaction
+func handleDeleteQuiz(request events.LambdaFunctionURLRequest) (events.LambdaFunctionURLResponse, error) {
+	quizName := strings.TrimSpace(request.QueryStringParameters["name"])
+	if quizName == "" {
+		return createErrorResponse(400, "Missing 'name' parameter"), nil
+	}
+
+	userEmail, err := getAuthenticatedEmail(request)
+	if err != nil {
+		log.Printf("❌ Token verification failed: %v", err)
+		return createErrorResponse(401, "Unauthorized"), nil
+	}
+
+	db, err := connectDB()
+	if err != nil {
+		log.Println("❌ Database connection error:", err)
+		return createErrorResponse(500, "Database connection failed"), nil
+	}
+	defer db.Close()
+
+	userRole, err := getUserRole(db, userEmail)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("❌ Failed to get user role: %v", err)
+		return createErrorResponse(500, "Failed to verify user permissions"), nil
+	}
+	if !hasRole(userRole, "admin", "super") {
+		return createErrorResponse(403, "Only 'admin' or 'super' role can delete quizzes"), nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Printf("❌ Failed to start transaction: %v", err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+	defer tx.Rollback()
+
+	// Soft-delete like bulk delete, so versions and doubts keep their quiz and
+	// an already deleted quiz is not found
+	result, err := tx.Exec(`
+		UPDATE quiz_questions SET deleted_at = NOW(), deleted_by = $2
+		WHERE quiz_name = $1 AND deleted_at IS NULL`, quizName, strings.ToLower(userEmail))
+	if err != nil {
+		log.Printf("❌ Failed to delete quiz %s: %v", quizName, err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return createErrorResponse(404, "Quiz not found"), nil
+	}
+	if err := recordContentChange(tx, ContentChangeDeleted, quizName, "", userEmail); err != nil {
+		log.Printf("❌ Failed to record delete of %s: %v", quizName, err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+
+	result, err = tx.Exec(`
+		UPDATE student_quizzes SET quiz_names = array_remove(quiz_names, $1)
+		WHERE $1 = ANY(quiz_names)`, quizName)
+	if err != nil {
+		log.Printf("❌ Failed to remove quiz %s from student_quizzes: %v", quizName, err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+	studentsUpdated, _ := result.RowsAffected()
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("❌ Failed to commit quiz delete: %v", err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+
+	log.Printf("🗑️ %s soft-deleted quiz %s (%d student references removed)", userEmail, quizName, studentsUpdated)
+	return createJSONResponse(200, map[string]interface{}{
+		"message":         "Quiz deleted successfully",
+		"studentsUpdated": studentsUpdated,
+	}), nil
+}
+
+// ✅ Handle Quiz Rename (admin/super): renames the quiz and rewrites every
+// reference to it in one transaction, so attempt history follows the new name
+func handleRenameQuiz(request events.LambdaFunctionURLRequest) (events.LambdaFunctionURLResponse, error) {
+	if request.RequestContext.HTTP.Method != "PUT" {
+		return createErrorResponse(405, "Method not allowed"), nil
+	}
+
+	userEmail, err := getAuthenticatedEmail(request)
+	if err != nil {
+		log.Printf("❌ Token verification failed: %v", err)
+		return createErrorResponse(401, "Unauthorized"), nil
+	}
+
+	var body struct {
+		OldName string `json:"oldName"`
+		NewName string `json:"newName"`
+	}
+	if err := json.Unmarshal([]byte(request.Body), &body); err != nil {
+		log.Println("❌ Error parsing JSON:", err)
+		return createErrorResponse(400, "Invalid JSON format"), nil
+	}
+	body.NewName = strings.TrimSpace(body.NewName)
+	if body.OldName == "" || body.NewName == "" {
+		return createErrorResponse(400, "Missing 'oldName' or 'newName' parameter"), nil
+	}
+	if body.OldName == body.NewName {
+		return createErrorResponse(400, "'newName' must differ from 'oldName'"), nil
+	}
+
+	db, err := connectDB()
+	if err != nil {
+		log.Println("❌ Database connection error:", err)
+		return createErrorResponse(500, "Database connection failed"), nil
+	}
+	defer db.Close()
+
+	userRole, err := getUserRole(db, userEmail)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("❌ Failed to get user role: %v", err)
+		return createErrorResponse(500, "Failed to verify user permissions"), nil
+	}
+	if !hasRole(userRole, "admin", "super") {
+		return createErrorResponse(403, "Only 'admin' or 'super' role can rename quizzes"), nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Printf("❌ Failed to start transaction: %v", err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+	defer tx.Rollback()
+
+	// Soft-deleted quizzes still hold their name
+	var taken bool
+	if err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM quiz_questions WHERE quiz_name = $1)", body.NewName).Scan(&taken); err != nil {
+		log.Printf("❌ Failed to check quiz name %s: %v", body.NewName, err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+	if taken {
+		return createErrorResponse(409, fmt.Sprintf("A quiz named %s already exists", body.NewName)), nil
+	}
+
+	result, err := tx.Exec("UPDATE quiz_questions SET quiz_name = $2 WHERE quiz_name = $1 AND deleted_at IS NULL", body.OldName, body.NewName)
+	if err != nil {
+		log.Printf("❌ Failed to rename quiz %s: %v", body.OldName, err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return createErrorResponse(404, "Quiz not found"), nil
+	}
+
+	result, err = tx.Exec(`
+		UPDATE student_quizzes SET quiz_names = array_replace(quiz_names, $1, $2)
+		WHERE $1 = ANY(quiz_names)`, body.OldName, body.NewName)
+	if err != nil {
+		log.Printf("❌ Failed to rename quiz %s in student_quizzes: %v", body.OldName, err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+	studentsUpdated, _ := result.RowsAffected()
+
+	for _, table := range []string{"doubts", "quiz_versions"} {
+		if _, err := tx.Exec("UPDATE "+table+" SET quiz_name = $2 WHERE quiz_name = $1", body.OldName, body.NewName); err != nil {
+			log.Printf("❌ Failed to rename quiz %s in %s: %v", body.OldName, table, err)
+			return createErrorResponse(500, "Internal server error"), nil
+		}
+	}
+	if err := recordContentChange(tx, ContentChangeRenamed, body.NewName, body.OldName, userEmail); err != nil {
+		log.Printf("❌ Failed to record rename of %s: %v", body.OldName, err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("❌ Failed to commit quiz rename: %v", err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+
+	log.Printf("✏️ %s renamed quiz %s to %s", userEmail, body.OldName, body.NewName)
+	return createJSONResponse(200, map[string]interface{}{
+		"message":         "Quiz renamed successfully",
+		"studentsUpdated": studentsUpdated,
+	}), nil
+}
+
+// ✅ Handle Quiz Versions (admin/super): GET ?name= lists saved versions, newest first
+func handleQuizVersions(request events.LambdaFunctionURLRequest) (events.LambdaFunctionURLResponse, error) {
+	if request.RequestContext.HTTP.Method != "GET" {
+		return createErrorResponse(405, "Method not allowed"), nil
+	}
+
+	quizName := request.QueryStringParameters["name"]
+	if quizName == "" {
+		return createErrorResponse(400, "Missing 'name' parameter"), nil
+	}
+
+	userEmail, err := getAuthenticatedEmail(request)
+	if err != nil {
+		log.Printf("❌ Token verification failed: %v", err)
+		return createErrorResponse(401, "Unauthorized"), nil
+	}
+
+	db, err := connectDB()
+	if err != nil {
+		log.Println("❌ Database connection error:", err)
+		return createErrorResponse(500, "Database connection failed"), nil
+	}
+	defer db.Close()
+
+	userRole, err := getUserRole(db, userEmail)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("❌ Failed to get user role: %v", err)
+		return createErrorResponse(500, "Failed to verify user permissions"), nil
+	}
+	if !hasRole(userRole, "admin", "super") {
+		return createErrorResponse(403, "Only 'admin' or 'super' role can view quiz versions"), nil
+	}
+
+	rows, err := db.Query(`
+		SELECT version, category, duration, jsonb_array_length(questions), uploaded_by, created_at
+		FROM quiz_versions WHERE quiz_name = $1 ORDER BY version DESC`, quizName)
+	if err != nil {
+		log.Printf("❌ Failed to fetch versions of %s: %v", quizName, err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+	defer rows.Close()
+
+	versions := []QuizVersion{}
+	for rows.Next() {
+		var version QuizVersion
+		err := rows.Scan(&version.Version, &version.Category, &version.Duration, &version.QuestionCount,
+			&version.UploadedBy, &version.CreatedAt)
+		if err != nil {
+			log.Printf("❌ Failed to read versions: %v", err)
+			return createErrorResponse(500, "Internal server error"), nil
+		}
+		versions = append(versions, version)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("❌ Failed to read versions: %v", err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+	if len(versions) == 0 {
+		return createErrorResponse(404, "No versions found for this quiz"), nil
+	}
+
+	return createJSONResponse(200, versions), nil
+}
+
+// ✅ Handle Quiz Rollback (admin/super): restores a saved version as the live
+// quiz. The rollback itself is recorded as a new version, so it can be undone
+func handleQuizRollback(request events.LambdaFunctionURLRequest) (events.LambdaFunctionURLResponse, error) {
+	if request.RequestContext.HTTP.Method != "POST" {
+		return createErrorResponse(405, "Method not allowed"), nil
+	}
+
+	userEmail, err := getAuthenticatedEmail(request)
+	if err != nil {
+		log.Printf("❌ Token verification failed: %v", err)
+		return createErrorResponse(401, "Unauthorized"), nil
+	}
+
+	var body struct {
+		Name    string `json:"name"`
+		Version int    `json:"version"`
+	}
+	if err := json.Unmarshal([]byte(request.Body), &body); err != nil {
+		log.Println("❌ Error parsing JSON:", err)
+		return createErrorResponse(400, "Invalid JSON format"), nil
+	}
+	if body.Name == "" || body.Version <= 0 {
+		return createErrorResponse(400, "Missing 'name' or 'version' parameter"), nil
+	}
+
+	db, err := connectDB()
+	if err != nil {
+		log.Println("❌ Database connection error:", err)
+		return createErrorResponse(500, "Database connection failed"), nil
+	}
+	defer db.Close()
+
+	userRole, err := getUserRole(db, userEmail)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("❌ Failed to get user role: %v", err)
+		return createErrorResponse(500, "Failed to verify user permissions"), nil
+	}
+	if !hasRole(userRole, "admin", "super") {
+		return createErrorResponse(403, "Only 'admin' or 'super' role can roll back quizzes"), nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Printf("❌ Failed to start transaction: %v", err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+	defer tx.Rollback()
+
+	var previousCategory string
+	err = tx.QueryRow("SELECT category FROM quiz_questions WHERE quiz_name = $1 AND deleted_at IS NULL FOR UPDATE", body.Name).Scan(&previousCategory)
+	if errors.Is(err, sql.ErrNoRows) {
+		return createErrorResponse(404, "Quiz or version not found"), nil
+	}
+	if err != nil {
+		log.Printf("❌ Failed to look up quiz %s: %v", body.Name, err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+
+	// Versions saved before metadata was snapshotted keep the current metadata,
+	// scoring and publish window
+	var category string
+	err = tx.QueryRow(`
+		UPDATE quiz_questions q
+		SET questions = v.questions, duration = v.duration, category = v.category, instructions = v.instructions,
+			metadata = COALESCE(v.metadata, q.metadata),
+			correct_marks = CASE WHEN v.metadata IS NULL THEN q.correct_marks ELSE v.correct_marks END,
+			wrong_penalty = CASE WHEN v.metadata IS NULL THEN q.wrong_penalty ELSE v.wrong_penalty END,
+			unanswered_marks = CASE WHEN v.metadata IS NULL THEN q.unanswered_marks ELSE v.unanswered_marks END,
+			publish_at = CASE WHEN v.metadata IS NULL THEN q.publish_at ELSE v.publish_at END,
+			close_at = CASE WHEN v.metadata IS NULL THEN q.close_at ELSE v.close_at END,
+			file_hash = NULL
+		FROM quiz_versions v
+		WHERE q.quiz_name = $1 AND q.deleted_at IS NULL AND v.quiz_name = $1 AND v.version = $2
+		RETURNING q.category`, body.Name, body.Version).Scan(&category)
+	if errors.Is(err, sql.ErrNoRows) {
+		return createErrorResponse(404, "Quiz or version not found"), nil
+	}
+	if err != nil {
+		log.Printf("❌ Failed to roll back %s to version %d: %v", body.Name, body.Version, err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+
+	newVersion, err := recordQuizVersion(tx, body.Name, strings.ToLower(userEmail))
+	if err != nil {
+		log.Printf("❌ Failed to version rollback of %s: %v", body.Name, err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+	if err := recordContentChange(tx, ContentChangeUpdated, body.Name, "", userEmail); err != nil {
+		log.Printf("❌ Failed to record rollback of %s: %v", body.Name, err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+	if category != previousCategory {
+		if err := recordContentChange(tx, ContentChangeCategoryChanged, body.Name, previousCategory, userEmail); err != nil {
+			log.Printf("❌ Failed to record rollback of %s: %v", body.Name, err)
+			return createErrorResponse(500, "Internal server error"), nil
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("❌ Failed to commit rollback: %v", err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+
+	log.Printf("⏪ %s rolled back %s to version %d (now version %d)", userEmail, body.Name, body.Version, newVersion)
+	return createJSONResponse(200, map[string]interface{}{
+		"message": fmt.Sprintf("Quiz rolled back to version %d", body.Version),
+		"version": newVersion,
+	}), nil
+}
+
+// ✅ Handle Quiz Duplicate (teacher/admin/super): copies a quiz's questions to a
+// new quiz, optionally in shuffled order and under another category
+func handleDuplicateQuiz(request events.LambdaFunctionURLRequest) (events.LambdaFunctionURLResponse, error) {
+	if request.RequestContext.HTTP.Method != "POST" {
+		return createErrorResponse(405, "Method not allowed"), nil
+	}
+
+	userEmail, err := getAuthenticatedEmail(request)
+	if err != nil {
+		log.Printf("❌ Token verification failed: %v", err)
+		return createErrorResponse(401, "Unauthorized"), nil
+	}
+
+	var body struct {
+		SourceQuizName string `json:"sourceQuizName"`
+		TargetQuizName string `json:"targetQuizName"`
+		Category       string `json:"category"`
+		Shuffle        bool   `json:"shuffle"`
+	}
+	if err := json.Unmarshal([]byte(request.Body), &body); err != nil {
+		log.Println("❌ Error parsing JSON:", err)
+		return createErrorResponse(400, "Invalid JSON format"), nil
+	}
+	body.TargetQuizName = strings.TrimSpace(body.TargetQuizName)
+	if body.SourceQuizName == "" || body.TargetQuizName == "" {
+		return createErrorResponse(400, "Missing 'sourceQuizName' or 'targetQuizName' parameter"), nil
+	}
+	if body.Category != "" && len(validCategories) > 0 && !containsString(validCategories, body.Category) {
+		return createJSONResponse(422, map[string]interface{}{
+			"error":       fmt.Sprintf("Invalid category: %s", body.Category),
+			"allowed":     validCategories,
+			"suggestions": suggestCategories(body.Category),
+		}), nil
+	}
+
+	db, err := connectDB()
+	if err != nil {
+		log.Println("❌ Database connection error:", err)
+		return createErrorResponse(500, "Database connection failed"), nil
+	}
+	defer db.Close()
+
+	userRole, err := getUserRole(db, userEmail)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("❌ Failed to get user role: %v", err)
+		return createErrorResponse(500, "Failed to verify user permissions"), nil
+	}
+	if !hasRole(userRole, "teacher", "admin", "super") {
+		return createErrorResponse(403, "Only 'teacher', 'admin' or 'super' role can duplicate quizzes"), nil
+	}
+
+	var quiz QuizData
+	var instructions sql.NullString
+	var questionsJSON []byte
+	var correctMarks, wrongPenalty, unansweredMarks sql.NullFloat64
+	err = db.QueryRow(`
+		SELECT category, duration, instructions, questions, correct_marks, wrong_penalty, unanswered_marks
+		FROM quiz_questions
+		WHERE quiz_name = $1 AND deleted_at IS NULL`, body.SourceQuizName).
+		Scan(&quiz.Category, &quiz.Duration, &instructions, &questionsJSON, &correctMarks, &wrongPenalty, &unansweredMarks)
+	if errors.Is(err, sql.ErrNoRows) {
+		return createErrorResponse(404, "Source quiz not found"), nil
+	}
+	if err != nil {
+		log.Printf("❌ Failed to fetch quiz %s: %v", body.SourceQuizName, err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+	if err := json.Unmarshal(questionsJSON, &quiz.Questions); err != nil {
+		log.Printf("❌ Failed to decode questions of %s: %v", body.SourceQuizName, err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+
+	quiz.QuizName = body.TargetQuizName
+	quiz.Instructions = instructions.String
+	if body.Category != "" {
+		quiz.Category = body.Category
+	}
+	if body.Shuffle {
+		rand.Shuffle(len(quiz.Questions), func(i, j int) {
+			quiz.Questions[i], quiz.Questions[j] = quiz.Questions[j], quiz.Questions[i]
+		})
+	}
+	questionsJSON, err = json.Marshal(quiz.Questions)
+	if err != nil {
+		log.Printf("❌ Failed to encode questions: %v", err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Printf("❌ Failed to start transaction: %v", err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+	defer tx.Rollback()
+
+	// The copy never overwrites an existing quiz (soft-deleted ones included)
+	result, err := tx.Exec(`
+		INSERT INTO quiz_questions (quiz_name, duration, category, questions, instructions,
+			correct_marks, wrong_penalty, unanswered_marks)
+		VALUES ($1, $2, $3, $4::jsonb, NULLIF($5, ''), $6, $7, $8)
+		ON CONFLICT (quiz_name) DO NOTHING`,
+		quiz.QuizName, quiz.Duration, quiz.Category, questionsJSON, quiz.Instructions,
+		correctMarks, wrongPenalty, unansweredMarks)
+	if err != nil {
+		log.Printf("❌ Failed to duplicate quiz %s: %v", body.SourceQuizName, err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return createErrorResponse(409, fmt.Sprintf("A quiz named %s already exists", quiz.QuizName)), nil
+	}
+	if _, err := recordQuizVersion(tx, quiz.QuizName, strings.ToLower(userEmail)); err != nil {
+		log.Printf("❌ Failed to version quiz %s: %v", quiz.QuizName, err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+	if err := recordContentChange(tx, ContentChangeCreated, quiz.QuizName, "", userEmail); err != nil {
+		log.Printf("❌ Failed to record creation of %s: %v", quiz.QuizName, err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("❌ Failed to commit quiz duplicate: %v", err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+
+	log.Printf("📄 %s duplicated quiz %s as %s", userEmail, body.SourceQuizName, quiz.QuizName)
+	return createJSONResponse(200, map[string]interface{}{
+		"message":       "Quiz duplicated successfully",
+		"quizName":      quiz.QuizName,
+		"questionCount": len(quiz.Questions),
+	}), nil
+}
+
+// ✅ Read quiz metadata from upload query parameters (tags comma-separated,
+// stored lowercase so tag filters are case-insensitive)
+func parseQuizMetadata(queryParams map[string]string) (QuizMetadata, error) {
+	metadata := QuizMetadata{
+		Topic:   strings.TrimSpace(queryParams["topic"]),
+		Chapter: strings.TrimSpace(queryParams["chapter"]),
+		Author:  strings.TrimSpace(queryParams["author"]),
+	}
+	if examYear := queryParams["examYear"]; examYear != "" {
+		year, err := strconv.Atoi(examYear)
+		if err != nil || year < 1900 || year > 2100 {
+			return QuizMetadata{}, errors.New("Invalid 'examYear'")
+		}
+		metadata.ExamYear = year
+	}
+	for _, tag := range strings.Split(queryParams["tags"], ",") {
+		if tag = strings.ToLower(strings.TrimSpace(tag)); tag != "" && !containsString(metadata.Tags, tag) {
+			metadata.Tags = append(metadata.Tags, tag)
+		}
+	}
+	return metadata, nil
+}
+
+// ✅ Parse optional RFC 3339 publishAt/closeAt values
+func parsePublishWindow(publishAtStr, closeAtStr string) (*time.Time, *time.Time, error) {
+	var publishAt, closeAt *time.Time
+	for _, bound := range []struct {
+		name, value string
+		target      **time.Time
+	}{{"publishAt", publishAtStr, &publishAt}, {"closeAt", closeAtStr, &closeAt}} {
+		if bound.value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, bound.value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Invalid '%s', expected RFC 3339 (e.g. 2026-01-05T06:00:00+05:30)", bound.name)
+		}
+		*bound.target = &parsed
+	}
+	if publishAt != nil && closeAt != nil && !closeAt.After(*publishAt) {
+		return nil, nil, errors.New("'closeAt' must be after 'publishAt'")
+	}
+	return publishAt, closeAt, nil
+}
+
+// ✅ Parse an optional marking scheme. Giving any value sets the whole scheme,
+// with 1 mark per correct answer and 0 otherwise by default
+func parseScoringConfig(correctStr, penaltyStr, unansweredStr string) (*ScoringConfig, error) {
+	if correctStr == "" && penaltyStr == "" && unansweredStr == "" {
+		return nil, nil
+	}
+	scoring := &ScoringConfig{CorrectMarks: 1}
+	for _, field := range []struct {
+		name, value string
+		target      *float64
+	}{
+		{"correctMarks", correctStr, &scoring.CorrectMarks},
+		{"wrongPenalty", penaltyStr, &scoring.WrongPenalty},
+		{"unansweredMarks", unansweredStr, &scoring.UnansweredMarks},
+	} {
+		if field.value == "" {
+			continue
+		}
+		value, err := strconv.ParseFloat(field.value, 64)
+		if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+			return nil, fmt.Errorf("Invalid '%s'", field.name)
+		}
+		*field.target = value
+	}
+	if scoring.CorrectMarks <= 0 {
+		return nil, errors.New("'correctMarks' must be positive")
+	}
+	if scoring.WrongPenalty < 0 {
+		return nil, errors.New("'wrongPenalty' must not be negative (it is deducted)")
+	}
+	return scoring, nil
+}
+
+// Helper function to build a marking scheme from its nullable columns
+func scoringFromColumns(correctMarks, wrongPenalty, unansweredMarks sql.NullFloat64) *ScoringConfig {
+	if !correctMarks.Valid {
+		return nil
+	}
+	return &ScoringConfig{
+		CorrectMarks:    correctMarks.Float64,
+		WrongPenalty:    wrongPenalty.Float64,
+		UnansweredMarks: unansweredMarks.Float64,
+	}
+}
+
+// ✅ Handle Quiz Scoring (admin/super): PATCH replaces a quiz's marking scheme
+func handleQuizScoring(request events.LambdaFunctionURLRequest) (events.LambdaFunctionURLResponse, error) {
+	if request.RequestContext.HTTP.Method != "PATCH" {
+		return createErrorResponse(405, "Method not allowed"), nil
+	}
+
+	userEmail, err := getAuthenticatedEmail(request)
+	if err != nil {
+		log.Printf("❌ Token verification failed: %v", err)
+		return createErrorResponse(401, "Unauthorized"), nil
+	}
+
+	var body struct {
+		Name string `json:"name"`
+		ScoringConfig
+	}
+	body.CorrectMarks = 1
+	if err := json.Unmarshal([]byte(request.Body), &body); err != nil {
+		log.Println("❌ Error parsing JSON:", err)
+		return createErrorResponse(400, "Invalid JSON format"), nil
+	}
+	if body.Name == "" {
+		return createErrorResponse(400, "Missing 'name' parameter"), nil
+	}
+	if body.CorrectMarks <= 0 {
+		return createErrorResponse(400, "'correctMarks' must be positive"), nil
+	}
+	if body.WrongPenalty < 0 {
+		return createErrorResponse(400, "'wrongPenalty' must not be negative (it is deducted)"), nil
+	}
+
+	db, err := connectDB()
+	if err != nil {
+		log.Println("❌ Database connection error:", err)
+		return createErrorResponse(500, "Database connection failed"), nil
+	}
+	defer db.Close()
+
+	userRole, err := getUserRole(db, userEmail)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("❌ Failed to get user role: %v", err)
+		return createErrorResponse(500, "Failed to verify user permissions"), nil
+	}
+	if !hasRole(userRole, "admin", "super") {
+		return createErrorResponse(403, "Only 'admin' or 'super' role can change quiz scoring"), nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Printf("❌ Failed to start transaction: %v", err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE quiz_questions SET correct_marks = $2, wrong_penalty = $3, unanswered_marks = $4
+		WHERE quiz_name = $1 AND deleted_at IS NULL`,
+		body.Name, body.CorrectMarks, body.WrongPenalty, body.UnansweredMarks)
+	if err != nil {
+		log.Printf("❌ Failed to update scoring of %s: %v", body.Name, err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return createErrorResponse(404, "Quiz not found"), nil
+	}
+	if err := recordContentChange(tx, ContentChangeUpdated, body.Name, "", userEmail); err != nil {
+		log.Printf("❌ Failed to record scoring change of %s: %v", body.Name, err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("❌ Failed to commit scoring change: %v", err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+
+	return createJSONResponse(200, map[string]interface{}{
+		"message": "Quiz scoring updated successfully",
+		"scoring": body.ScoringConfig,
+	}), nil
+}
+
+// ✅ Handle Quiz Window (admin/super): PATCH sets both publishAt and closeAt of
+// a quiz; an omitted or empty value removes that bound
+func handleQuizWindow(request events.LambdaFunctionURLRequest) (events.LambdaFunctionURLResponse, error) {
+	if request.RequestContext.HTTP.Method != "PATCH" {
+		return createErrorResponse(405, "Method not allowed"), nil
+	}
+
+	userEmail, err := getAuthenticatedEmail(request)
+	if err != nil {
+		log.Printf("❌ Token verification failed: %v", err)
+		return createErrorResponse(401, "Unauthorized"), nil
+	}
+
+	var body struct {
+		Name      string `json:"name"`
+		PublishAt string `json:"publishAt"`
+		CloseAt   string `json:"closeAt"`
+	}
+	if err := json.Unmarshal([]byte(request.Body), &body); err != nil {
+		log.Println("❌ Error parsing JSON:", err)
+		return createErrorResponse(400, "Invalid JSON format"), nil
+	}
+	if body.Name == "" {
+		return createErrorResponse(400, "Missing 'name' parameter"), nil
+	}
+	publishAt, closeAt, err := parsePublishWindow(body.PublishAt, body.CloseAt)
+	if err != nil {
+		return createErrorResponse(400, err.Error()), nil
+	}
+
+	db, err := connectDB()
+	if err != nil {
+		log.Println("❌ Database connection error:", err)
+		return createErrorResponse(500, "Database connection failed"), nil
+	}
+	defer db.Close()
+
+	userRole, err := getUserRole(db, userEmail)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("❌ Failed to get user role: %v", err)
+		return createErrorResponse(500, "Failed to verify user permissions"), nil
+	}
+	if !hasRole(userRole, "admin", "super") {
+		return createErrorResponse(403, "Only 'admin' or 'super' role can schedule quizzes"), nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Printf("❌ Failed to start transaction: %v", err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE quiz_questions SET publish_at = $2, close_at = $3
+		WHERE quiz_name = $1 AND deleted_at IS NULL`, body.Name, publishAt, closeAt)
+	if err != nil {
+		log.Printf("❌ Failed to update window of %s: %v", body.Name, err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return createErrorResponse(404, "Quiz not found"), nil
+	}
+	if err := recordContentChange(tx, ContentChangeUpdated, body.Name, "", userEmail); err != nil {
+		log.Printf("❌ Failed to record window change of %s: %v", body.Name, err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("❌ Failed to commit window change: %v", err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+
+	return createJSONResponse(200, map[string]interface{}{
+		"message":   "Quiz window updated successfully",
+		"publishAt": publishAt,
+		"closeAt":   closeAt,
+	}), nil
+}
+
+// ✅ Handle Question Bank Summary (teacher/admin/super): question counts per
+// topic and difficulty in a ?category=
+func handleQuestionBankSummary(request events.LambdaFunctionURLRequest) (events.LambdaFunctionURLResponse, error) {
+	if request.RequestContext.HTTP.Method != "GET" {
+		return createErrorResponse(405, "Method not allowed"), nil
+	}
+
+	userEmail, err := getAuthenticatedEmail(request)
+	if err != nil {
+		log.Printf("❌ Token verification failed: %v", err)
+		return createErrorResponse(401, "Unauthorized"), nil
+	}
+
+	category := request.QueryStringParameters["category"]
+	if category == "" {
+		return createErrorResponse(400, "Missing 'category' parameter"), nil
+	}
+
+	db, err := connectDB()
+	if err != nil {
+		log.Println("❌ Database connection error:", err)
+		return createErrorResponse(500, "Database connection failed"), nil
+	}
+	defer db.Close()
+
+	userRole, err := getUserRole(db, userEmail)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("❌ Failed to get user role: %v", err)
+		return createErrorResponse(500, "Failed to verify user permissions"), nil
+	}
+	if !hasRole(userRole, "teacher", "admin", "super") {
+		return createErrorResponse(403, "Only 'teacher', 'admin' or 'super' role can view the question bank"), nil
+	}
+
+	rows, err := db.Query(`
+		SELECT topic, difficulty, COUNT(*) FROM question_bank
+		WHERE category = $1
+		GROUP BY topic, difficulty
+		ORDER BY topic, difficulty`, category)
+	if err != nil {
+		log.Printf("❌ Failed to fetch question bank summary: %v", err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+	defer rows.Close()
+
+	summary := []BankSummary{}
+	for rows.Next() {
+		var bucket BankSummary
+		if err := rows.Scan(&bucket.Topic, &bucket.Difficulty, &bucket.Questions); err != nil {
+			log.Printf("❌ Failed to read question bank summary: %v", err)
+			return createErrorResponse(500, "Internal server error"), nil
+		}
+		summary = append(summary, bucket)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("❌ Failed to read question bank summary: %v", err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+
+	return createJSONResponse(200, summary), nil
+}
+
+// ✅ Handle Quiz Assembly (teacher/admin/super): samples questions from the bank
+// into a new draft quiz. Fails with the shortfall if a section can't be filled
+func handleAssembleQuiz(request events.LambdaFunctionURLRequest) (events.LambdaFunctionURLResponse, error) {
+	if request.RequestContext.HTTP.Method != "POST" {
+		return createErrorResponse(405, "Method not allowed"), nil
+	}
+
+	userEmail, err := getAuthenticatedEmail(request)
+	if err != nil {
+		log.Printf("❌ Token verification failed: %v", err)
+		return createErrorResponse(401, "Unauthorized"), nil
+	}
+
+	var body AssembleQuizRequest
+	if err := json.Unmarshal([]byte(request.Body), &body); err != nil {
+		log.Println("❌ Error parsing JSON:", err)
+		return createErrorResponse(400, "Invalid JSON format"), nil
+	}
+	body.QuizName = strings.TrimSpace(body.QuizName)
+	if body.QuizName == "" || body.Category == "" || body.Duration <= 0 || len(body.Sections) == 0 {
+		return createErrorResponse(400, "Missing 'quizName', 'category', 'duration' or 'sections' parameter"), nil
+	}
+	for _, section := range body.Sections {
+		if section.Count <= 0 {
+			return createErrorResponse(400, "Each section needs a positive 'count'"), nil
+		}
+		if section.Difficulty != "" && !containsString(validDifficulties, section.Difficulty) {
+			return createErrorResponse(400, "difficulty must be 'easy', 'medium' or 'hard'"), nil
+		}
+	}
+
+	db, err := connectDB()
+	if err != nil {
+		log.Println("❌ Database connection error:", err)
+		return createErrorResponse(500, "Database connection failed"), nil
+	}
+	defer db.Close()
+
+	userRole, err := getUserRole(db, userEmail)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("❌ Failed to get user role: %v", err)
+		return createErrorResponse(500, "Failed to verify user permissions"), nil
+	}
+	if !hasRole(userRole, "teacher", "admin", "super") {
+		return createErrorResponse(403, "Only 'teacher', 'admin' or 'super' role can assemble quizzes"), nil
+	}
+
+	// ✅ Sample each section, never picking the same bank question twice
+	var questions []Question
+	pickedIDs := []int64{} // pq sends a nil slice as NULL, which would match nothing
+	var shortfalls []map[string]interface{}
+	for _, section := range body.Sections {
+		rows, err := db.Query(`
+			SELECT id, question FROM question_bank
+			WHERE category = $1 AND ($2 = '' OR topic = $2) AND ($3 = '' OR difficulty = $3)
+				AND NOT id = ANY($4)
+			ORDER BY random()
+			LIMIT $5`, body.Category, section.Topic, section.Difficulty, pq.Array(pickedIDs), section.Count)
+		if err != nil {
+			log.Printf("❌ Failed to sample question bank: %v", err)
+			return createErrorResponse(500, "Internal server error"), nil
+		}
+		found := 0
+		for rows.Next() {
+			var id int64
+			var questionJSON []byte
+			var question Question
+			err := rows.Scan(&id, &questionJSON)
+			if err == nil {
+				err = json.Unmarshal(questionJSON, &question)
+			}
+			if err != nil {
+				rows.Close()
+				log.Printf("❌ Failed to read question bank: %v", err)
+				return createErrorResponse(500, "Internal server error"), nil
+			}
+			pickedIDs = append(pickedIDs, id)
+			questions = append(questions, question)
+			found++
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			log.Printf("❌ Failed to read question bank: %v", err)
+			return createErrorResponse(500, "Internal server error"), nil
+		}
+		if found < section.Count {
+			shortfalls = append(shortfalls, map[string]interface{}{
+				"topic":      section.Topic,
+				"difficulty": section.Difficulty,
+				"requested":  section.Count,
+				"available":  found,
+			})
+		}
+	}
+	if len(shortfalls) > 0 {
+		return createJSONResponse(422, map[string]interface{}{
+			"error":    "Not enough questions in the bank",
+			"sections": shortfalls,
+		}), nil
+	}
+
+	questionsJSON, err := json.Marshal(questions)
+	if err != nil {
+		log.Printf("❌ Failed to encode questions: %v", err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Printf("❌ Failed to start transaction: %v", err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		INSERT INTO quiz_questions (quiz_name, duration, category, questions, instructions)
+		VALUES ($1, $2, $3, $4::jsonb, NULLIF($5, ''))
+		ON CONFLICT (quiz_name) DO NOTHING`,
+		body.QuizName, body.Duration, body.Category, questionsJSON, body.Instructions)
+	if err != nil {
+		log.Printf("❌ Failed to save assembled quiz %s: %v", body.QuizName, err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return createErrorResponse(409, fmt.Sprintf("A quiz named %s already exists", body.QuizName)), nil
+	}
+	if _, err := recordQuizVersion(tx, body.QuizName, strings.ToLower(userEmail)); err != nil {
+		log.Printf("❌ Failed to version quiz %s: %v", body.QuizName, err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+	if err := recordContentChange(tx, ContentChangeCreated, body.QuizName, "", userEmail); err != nil {
+		log.Printf("❌ Failed to record creation of %s: %v", body.QuizName, err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("❌ Failed to commit assembled quiz: %v", err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+
+	log.Printf("🧩 %s assembled %s with %d question(s) from the question bank", userEmail, body.QuizName, len(questions))
+	return createJSONResponse(200, map[string]interface{}{
+		"message":       "Quiz assembled successfully",
+		"quizName":      body.QuizName,
+		"questionCount": len(questions),
+		"status":        QuizStatusDraft,
+	}), nil
+}
+
+// ✅ Handle Question Bulk Tag (teacher/admin/super): an .xlsx with an ID,
+// QuestionHash or Question (plus optional Category) column and Topic and/or
+// Difficulty retags existing bank questions. Every row is validated and matched
+// before anything is written, then updates are applied in chunks within one
+// transaction. Rows matching several questions, or the same question as another
+// row, reject the upload
+func handleBulkTagQuestions(request events.LambdaFunctionURLRequest) (events.LambdaFunctionURLResponse, error) {
+	if request.RequestContext.HTTP.Method != "POST" {
+		return createErrorResponse(405, "Method not allowed"), nil
+	}
+
+	userEmail, err := getAuthenticatedEmail(request)
+	if err != nil {
+		log.Printf("❌ Token verification failed: %v", err)
+		return createErrorResponse(401, "Unauthorized"), nil
+	}
+
+	if base64.StdEncoding.DecodedLen(len(request.Body)) > MaxUploadBytes {
+		return createErrorResponse(413, fmt.Sprintf("File exceeds the %d byte upload limit", MaxUploadBytes)), nil
+	}
+	fileContent, err := base64.StdEncoding.DecodeString(request.Body)
+	if err != nil {
+		return createErrorResponse(400, "Invalid file encoding"), nil
+	}
+	if detectFileFormat(fileContent) != fileFormatXLSX {
+		return createErrorResponse(415, "Unsupported file format, expected .xlsx"), nil
+	}
+
+	updates, rowErrors, err := parseBulkTagSheet(fileContent)
+	if err != nil {
+		log.Printf("❌ Failed to read bulk-tag sheet: %v", err)
+		return createErrorResponse(400, err.Error()), nil
+	}
+	if len(rowErrors) > 0 {
+		return createJSONResponse(422, map[string]interface{}{
+			"error": "Uploaded file contains invalid rows",
+			"rows":  rowErrors,
+		}), nil
+	}
+	if len(updates) == 0 {
+		return createErrorResponse(400, "No rows to update"), nil
+	}
+
+	db, err := connectDB()
+	if err != nil {
+		log.Println("❌ Database connection error:", err)
+		return createErrorResponse(500, "Database connection failed"), nil
+	}
+	defer db.Close()
+
+	userRole, err := getUserRole(db, userEmail)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("❌ Failed to get user role: %v", err)
+		return createErrorResponse(500, "Failed to verify user permissions"), nil
+	}
+	if !hasRole(userRole, "teacher", "admin", "super") {
+		return createErrorResponse(403, "Only 'teacher', 'admin' or 'super' role can tag questions"), nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Printf("❌ Failed to start transaction: %v", err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+	defer tx.Rollback()
+
+	matches, err := resolveBankTagRows(tx, updates)
+	if err != nil {
+		log.Printf("❌ Failed to match bulk tag rows: %v", err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+	if conflicts := bankTagConflicts(updates, matches); len(conflicts) > 0 {
+		return createJSONResponse(422, map[string]interface{}{
+			"error": "Some rows match ambiguously",
+			"rows":  conflicts,
+		}), nil
+	}
+
+	notFound := []int{}
+	var resolved []BankTagUpdate
+	for _, update := range updates {
+		if len(matches[update.Row]) == 0 {
+			notFound = append(notFound, update.Row)
+			continue
+		}
+		update.ID = matches[update.Row][0]
+		resolved = append(resolved, update)
+	}
+
+	for start := 0; start < len(resolved); start += bankTagChunkSize {
+		chunk := resolved[start:min(start+bankTagChunkSize, len(resolved))]
+		ids := make([]int64, len(chunk))
+		topics := make([]string, len(chunk))
+		difficulties := make([]string, len(chunk))
+		for i, update := range chunk {
+			ids[i], topics[i], difficulties[i] = update.ID, update.Topic, update.Difficulty
+		}
+
+		_, err := tx.Exec(`
+			UPDATE question_bank b
+			SET topic = COALESCE(NULLIF(u.topic, ''), b.topic),
+				difficulty = COALESCE(NULLIF(u.difficulty, ''), b.difficulty),
+				updated_at = NOW()
+			FROM unnest($1::bigint[], $2::text[], $3::text[]) AS u(id, topic, difficulty)
+			WHERE b.id = u.id`,
+			pq.Array(ids), pq.Array(topics), pq.Array(difficulties))
+		if err != nil {
+			log.Printf("❌ Failed to apply bulk tags: %v", err)
+			return createErrorResponse(500, "Internal server error"), nil
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("❌ Failed to commit bulk tags: %v", err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+
+	log.Printf("🏷️ %s retagged %d bank question(s), %d row(s) unmatched", userEmail, len(resolved), len(notFound))
+	return createJSONResponse(200, map[string]interface{}{
+		"message":      fmt.Sprintf("%d question(s) tagged", len(resolved)),
+		"updated":      len(resolved),
+		"notFoundRows": notFound,
+	}), nil
+}
+
+// ✅ Find the bank question ids each bulk-tag row refers to (by sheet row),
+// locking them until the transaction ends
+func resolveBankTagRows(tx *sql.Tx, updates []BankTagUpdate) (map[int][]int64, error) {
+	matches := make(map[int][]int64, len(updates))
+	for start := 0; start < len(updates); start += bankTagChunkSize {
+		chunk := updates[start:min(start+bankTagChunkSize, len(updates))]
+		rows := make([]int64, len(chunk))
+		ids := make([]int64, len(chunk))
+		hashes := make([]string, len(chunk))
+		questionKeys := make([]string, len(chunk))
+		categories := make([]string, len(chunk))
+		for i, update := range chunk {
+			rows[i], ids[i], hashes[i], questionKeys[i], categories[i] =
+				int64(update.Row), update.ID, update.Hash, update.QuestionKey, update.Category
+		}
+
+		// Question text is normalized like questionKey: trimmed, single-spaced, lowercase
+		result, err := tx.Query(`
+			SELECT u.row_number, b.id
+			FROM unnest($1::bigint[], $2::bigint[], $3::text[], $4::text[], $5::text[])
+				AS u(row_number, id, question_hash, question_key, category)
+			JOIN question_bank b ON CASE
+				WHEN u.id > 0 THEN b.id = u.id
+				WHEN u.question_hash <> '' THEN b.question_hash = u.question_hash
+				ELSE lower(regexp_replace(btrim(b.question->>'question'), '\s+', ' ', 'g')) = u.question_key
+					AND (u.category = '' OR b.category = u.category)
+			END
+			ORDER BY u.row_number, b.id
+			FOR UPDATE OF b`,
+			pq.Array(rows), pq.Array(ids), pq.Array(hashes), pq.Array(questionKeys), pq.Array(categories))
+		if err != nil {
+			return nil, err
+		}
+		for result.Next() {
+			var row int
+			var id int64
+			if err := result.Scan(&row, &id); err != nil {
+				result.Close()
+				return nil, err
+			}
+			matches[row] = append(matches[row], id)
+		}
+		result.Close()
+		if err := result.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return matches, nil
+}
+
+// ✅ Report rows that match several bank questions, and rows that target a
+// question another row already targets
+func bankTagConflicts(updates []BankTagUpdate, matches map[int][]int64) []RowError {
+	var conflicts []RowError
+	firstRowByID := make(map[int64]int)
+	for _, update := range updates {
+		ids := matches[update.Row]
+		if len(ids) > 1 {
+			conflicts = append(conflicts, RowError{
+				Row:    update.Row,
+				Reason: fmt.Sprintf("matches %d bank questions (ids %s); use ID or QuestionHash", len(ids), joinIDs(ids)),
+			})
+			continue
+		}
+		if len(ids) == 1 {
+			if firstRow, exists := firstRowByID[ids[0]]; exists {
+				conflicts = append(conflicts, RowError{
+					Row:    update.Row,
+					Reason: fmt.Sprintf("targets the same bank question (id %d) as row %d", ids[0], firstRow),
+				})
+				continue
+			}
+			firstRowByID[ids[0]] = update.Row
+		}
+	}
+	return conflicts
+}
+
+// Helper function to list ids as "3, 9"
+func joinIDs(ids []int64) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.FormatInt(id, 10)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ✅ Read and validate a bulk-tag sheet (first sheet, header row first)
+func parseBulkTagSheet(fileBytes []byte) ([]BankTagUpdate, []RowError, error) {
+	f, err := excelize.OpenReader(bytes.NewReader(fileBytes))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	rows, err := f.GetRows(f.GetSheetName(0))
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil, errors.New("Sheet is empty")
+	}
+	if len(rows)-1 > MaxUploadRows {
+		return nil, nil, fmt.Errorf("Sheet has more than %d rows", MaxUploadRows)
+	}
+
+	headerMap := make(map[string]int)
+	for i, header := range rows[0] {
+		switch normalizeHeader(header) {
+		case "id":
+			headerMap["ID"] = i
+		case "questionhash", "hash":
+			headerMap["QuestionHash"] = i
+		case "question":
+			headerMap["Question"] = i
+		case "category":
+			headerMap["Category"] = i
+		case "topic":
+			headerMap["Topic"] = i
+		case "difficulty":
+			headerMap["Difficulty"] = i
+		}
+	}
+	_, hasID := headerMap["ID"]
+	_, hasHash := headerMap["QuestionHash"]
+	_, hasQuestion := headerMap["Question"]
+	_, hasTopic := headerMap["Topic"]
+	_, hasDifficulty := headerMap["Difficulty"]
+	if !hasID && !hasHash && !hasQuestion {
+		return nil, nil, errors.New("Missing required column: ID, QuestionHash or Question")
+	}
+	if !hasTopic && !hasDifficulty {
+		return nil, nil, errors.New("Missing required column: Topic or Difficulty")
+	}
+
+	var updates []BankTagUpdate
+	var rowErrors []RowError
+	seen := make(map[string]int)
+	for i, row := range rows[1:] {
+		if isBlankRow(row) {
+			continue
+		}
+		update := BankTagUpdate{
+			Row:        i + 2,
+			Hash:       strings.ToLower(strings.TrimSpace(getCellValue(row, headerMap, "QuestionHash"))),
+			Topic:      strings.TrimSpace(getCellValue(row, headerMap, "Topic")),
+			Difficulty: strings.ToLower(strings.TrimSpace(getCellValue(row, headerMap, "Difficulty"))),
+		}
+		key := update.Hash
+		if update.Hash == "" {
+			update.QuestionKey = questionKey(Question{Question: getCellValue(row, headerMap, "Question")})
+			update.Category = strings.TrimSpace(getCellValue(row, headerMap, "Category"))
+			key = "question:" + update.Category + "\n" + update.QuestionKey
+		}
+		if id := strings.TrimSpace(getCellValue(row, headerMap, "ID")); id != "" {
+			update.ID, err = strconv.ParseInt(id, 10, 64)
+			if err != nil || update.ID <= 0 {
+				rowErrors = append(rowErrors, RowError{Row: update.Row, Column: "ID", Reason: "ID must be a positive whole number"})
+				continue
+			}
+			key = "id:" + id
+		}
+		if update.ID == 0 && update.Hash == "" && update.QuestionKey == "" {
+			rowErrors = append(rowErrors, RowError{Row: update.Row, Reason: "Row needs an ID, QuestionHash or Question"})
+			continue
+		}
+		if update.Difficulty != "" && !containsString(validDifficulties, update.Difficulty) {
+			rowErrors = append(rowErrors, RowError{Row: update.Row, Column: "Difficulty", Reason: "Difficulty must be easy, medium or hard"})
+			continue
+		}
+		if update.Topic == "" && update.Difficulty == "" {
+			rowErrors = append(rowErrors, RowError{Row: update.Row, Reason: "Row has neither Topic nor Difficulty"})
+			continue
+		}
+		if firstRow, exists := seen[key]; exists {
+			rowErrors = append(rowErrors, RowError{Row: update.Row, Reason: fmt.Sprintf("duplicate of row %d", firstRow)})
+			continue
+		}
+		seen[key] = update.Row
+		updates = append(updates, update)
+	}
+	return updates, rowErrors, nil
+}
+
+// ✅ Handle Content Calendar (teacher/admin/super): for each daily quiz category,
+// which of the next ?days= (default 30) dates have a quiz opening and which are
+// gaps. Quizzes without publishAt open on the day they were uploaded
+func handleContentCalendar(request events.LambdaFunctionURLRequest) (events.LambdaFunctionURLResponse, error) {
+	if request.RequestContext.HTTP.Method != "GET" {
+		return createErrorResponse(405, "Method not allowed"), nil
+	}
+
+	userEmail, err := getAuthenticatedEmail(request)
+	if err != nil {
+		log.Printf("❌ Token verification failed: %v", err)
+		return createErrorResponse(401, "Unauthorized"), nil
+	}
+
+	days := 30
+	if value := request.QueryStringParameters["days"]; value != "" {
+		days, err = strconv.Atoi(value)
+		if err != nil || days <= 0 || days > 90 {
+			return createErrorResponse(400, "Invalid 'days' (1-90)"), nil
+		}
+	}
+	categories := dailyQuizCategories
+	if category := request.QueryStringParameters["category"]; category != "" {
+		categories = []string{category}
+	}
+	if len(categories) == 0 {
+		return createErrorResponse(400, "No daily quiz categories configured; pass 'category'"), nil
+	}
+
+	db, err := connectDB()
+	if err != nil {
+		log.Println("❌ Database connection error:", err)
+		return createErrorResponse(500, "Database connection failed"), nil
+	}
+	defer db.Close()
+
+	userRole, err := getUserRole(db, userEmail)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("❌ Failed to get user role: %v", err)
+		return createErrorResponse(500, "Failed to verify user permissions"), nil
+	}
+	if !hasRole(userRole, "teacher", "admin", "super") {
+		return createErrorResponse(403, "Only 'teacher', 'admin' or 'super' role can view the content calendar"), nil
+	}
+
+	now := time.Now().In(calendarLocation)
+	from := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, calendarLocation)
+	to := from.AddDate(0, 0, days)
+
+	rows, err := db.Query(`
+		SELECT category, to_char(COALESCE(publish_at, created_at) AT TIME ZONE $4, 'YYYY-MM-DD'),
+			COUNT(*) FILTER (WHERE status = $5), COUNT(*) FILTER (WHERE status = $6)
+		FROM quiz_questions
+		WHERE deleted_at IS NULL AND category = ANY($1)
+			AND COALESCE(publish_at, created_at) >= $2 AND COALESCE(publish_at, created_at) < $3
+		GROUP BY 1, 2`,
+		pq.Array(categories), from, to, calendarLocation.String(), QuizStatusPublished, QuizStatusDraft)
+	if err != nil {
+		log.Printf("❌ Failed to fetch content calendar: %v", err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+	defer rows.Close()
+
+	byCategory := map[string]map[string]CalendarDay{}
+	for rows.Next() {
+		var category string
+		var day CalendarDay
+		if err := rows.Scan(&category, &day.Date, &day.Published, &day.Drafts); err != nil {
+			log.Printf("❌ Failed to read content calendar: %v", err)
+			return createErrorResponse(500, "Internal server error"), nil
+		}
+		if byCategory[category] == nil {
+			byCategory[category] = map[string]CalendarDay{}
+		}
+		byCategory[category][day.Date] = day
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("❌ Failed to read content calendar: %v", err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+
+	calendar := make([]CategoryCalendar, 0, len(categories))
+	for _, category := range categories {
+		entry := CategoryCalendar{Category: category, Days: []CalendarDay{}, Missing: []string{}}
+		for date := from; date.Before(to); date = date.AddDate(0, 0, 1) {
+			key := date.Format("2006-01-02")
+			day, ok := byCategory[category][key]
+			if ok {
+				entry.Days = append(entry.Days, day)
+			}
+			if day.Published == 0 {
+				entry.Missing = append(entry.Missing, key)
+			}
+		}
+		calendar = append(calendar, entry)
+	}
+
+	return createJSONResponse(200, map[string]interface{}{
+		"from":       from.Format("2006-01-02"),
+		"to":         to.AddDate(0, 0, -1).Format("2006-01-02"),
+		"timezone":   calendarLocation.String(),
+		"categories": calendar,
+	}), nil
+}
+
+// ✅ Handle Quiz Publish (admin/super): moves a quiz between draft, published and
+// archived. Status defaults to "published"
+func handleQuizPublish(request events.LambdaFunctionURLRequest) (events.LambdaFunctionURLResponse, error) {
+	if request.RequestContext.HTTP.Method != "POST" {
+		return createErrorResponse(405, "Method not allowed"), nil
+	}
+
+	userEmail, err := getAuthenticatedEmail(request)
+	if err != nil {
+		log.Printf("❌ Token verification failed: %v", err)
+		return createErrorResponse(401, "Unauthorized"), nil
+	}
+
+	var body struct {
+		Name   string `json:"name"`
+		Status string `json:"status"`
+	}
+	if err := json.Unmarshal([]byte(request.Body), &body); err != nil {
+		log.Println("❌ Error parsing JSON:", err)
+		return createErrorResponse(400, "Invalid JSON format"), nil
+	}
+	if body.Name == "" {
+		return createErrorResponse(400, "Missing 'name' parameter"), nil
+	}
+	if body.Status == "" {
+		body.Status = QuizStatusPublished
+	}
+	if body.Status != QuizStatusDraft && body.Status != QuizStatusPublished && body.Status != QuizStatusArchived {
+		return createErrorResponse(400, "Invalid 'status' (use draft, published or archived)"), nil
+	}
+
+	db, err := connectDB()
+	if err != nil {
+		log.Println("❌ Database connection error:", err)
+		return createErrorResponse(500, "Database connection failed"), nil
+	}
+	defer db.Close()
+
+	userRole, err := getUserRole(db, userEmail)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("❌ Failed to get user role: %v", err)
+		return createErrorResponse(500, "Failed to verify user permissions"), nil
+	}
+	if !hasRole(userRole, "admin", "super") {
+		return createErrorResponse(403, "Only 'admin' or 'super' role can publish quizzes"), nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Printf("❌ Failed to start transaction: %v", err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE quiz_questions SET status = $2
+		WHERE quiz_name = $1 AND deleted_at IS NULL`, body.Name, body.Status)
+	if err != nil {
+		log.Printf("❌ Failed to set status of %s: %v", body.Name, err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return createErrorResponse(404, "Quiz not found"), nil
+	}
+
+	if err := recordContentChange(tx, ContentChangeStatus, body.Name, body.Status, userEmail); err != nil {
+		log.Printf("❌ Failed to record status change of %s: %v", body.Name, err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("❌ Failed to commit status change: %v", err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+
+	log.Printf("📢 %s set %s to %s", userEmail, body.Name, body.Status)
+	return createJSONResponse(200, map[string]interface{}{
+		"message": fmt.Sprintf("Quiz is now %s", body.Status),
+		"status":  body.Status,
+	}), nil
+}
+
+// ✅ Handle Bulk Quiz Delete (admin/super). The first call returns a preview and a
+// confirmation token; repeating it with the token soft-deletes exactly that set
+func handleBulkDeleteQuizzes(request events.LambdaFunctionURLRequest) (events.LambdaFunctionURLResponse, error) {
+	if request.RequestContext.HTTP.Method != "POST" {
+		return createErrorResponse(405, "Method not allowed"), nil
+	}
+
+	userEmail, err := getAuthenticatedEmail(request)
+	if err != nil {
+		log.Printf("❌ Token verification failed: %v", err)
+		return createErrorResponse(401, "Unauthorized"), nil
+	}
+
+	var body BulkDeleteRequest
+	if err := json.Unmarshal([]byte(request.Body), &body); err != nil {
+		log.Println("❌ Error parsing JSON:", err)
+		return createErrorResponse(400, "Invalid JSON format"), nil
+	}
+
+	// ✅ Build the filter; an empty filter would match every quiz
+	where := "deleted_at IS NULL"
+	params := []interface{}{}
+	if body.Category != "" {
+		params = append(params, body.Category)
+		where += fmt.Sprintf(" AND category = $%d", len(params))
+	}
+	if body.NamePattern != "" {
+		params = append(params, strings.ReplaceAll(escapeLike(body.NamePattern), "*", "%"))
+		where += fmt.Sprintf(" AND quiz_name ILIKE $%d", len(params))
+	}
+	for _, bound := range []struct{ name, value, condition string }{
+		{"createdFrom", body.CreatedFrom, ">="},
+		{"createdTo", body.CreatedTo, "<"},
+	} {
+		if bound.value == "" {
+			continue
+		}
+		date, err := time.Parse("2006-01-02", bound.value)
+		if err != nil {
+			return createErrorResponse(400, fmt.Sprintf("Invalid '%s', expected YYYY-MM-DD", bound.name)), nil
+		}
+		if bound.name == "createdTo" {
+			// Make the end date inclusive
+			date = date.AddDate(0, 0, 1)
+		}
+		params = append(params, date)
+		where += fmt.Sprintf(" AND created_at %s $%d", bound.condition, len(params))
+	}
+	if len(params) == 0 {
+		return createErrorResponse(400, "At least one of 'category', 'namePattern', 'createdFrom' or 'createdTo' is required"), nil
+	}
+
+	db, err := connectDB()
+	if err != nil {
+		log.Println("❌ Database connection error:", err)
+		return createErrorResponse(500, "Database connection failed"), nil
+	}
+	defer db.Close()
+
+	userRole, err := getUserRole(db, userEmail)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("❌ Failed to get user role: %v", err)
+		return createErrorResponse(500, "Failed to verify user permissions"), nil
+	}
+	if !hasRole(userRole, "admin", "super") {
+		return createErrorResponse(403, "Only 'admin' or 'super' role can delete quizzes"), nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Printf("❌ Failed to start transaction: %v", err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+	defer tx.Rollback()
+
+	quizNames, err := queryStrings(tx, "SELECT quiz_name FROM quiz_questions WHERE "+where+" ORDER BY quiz_name FOR UPDATE", params...)
+	if err != nil {
+		log.Printf("❌ Failed to match quizzes for bulk delete: %v", err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+
+	confirmToken := bulkDeleteToken(quizNames)
+
+	if body.ConfirmToken == "" {
+		return createJSONResponse(200, map[string]interface{}{
+			"count":        len(quizNames),
+			"quizNames":    quizNames,
+			"confirmToken": confirmToken,
+		}), nil
+	}
+	if body.ConfirmToken != confirmToken {
+		return createErrorResponse(409, "The matching quizzes changed since the preview, please preview again"), nil
+	}
+
+	_, err = tx.Exec("UPDATE quiz_questions SET deleted_at = NOW(), deleted_by = $1 WHERE quiz_name = ANY($2)",
+		strings.ToLower(userEmail), pq.Array(quizNames))
+	if err != nil {
+		log.Printf("❌ Failed to bulk delete quizzes: %v", err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+	for _, quizName := range quizNames {
+		if err := recordContentChange(tx, ContentChangeDeleted, quizName, "", userEmail); err != nil {
+			log.Printf("❌ Failed to record delete of %s: %v", quizName, err)
+			return createErrorResponse(500, "Internal server error"), nil
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("❌ Failed to commit bulk delete: %v", err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+
+	log.Printf("🗑️ %s soft-deleted %d quizzes", userEmail, len(quizNames))
+	return createJSONResponse(200, map[string]interface{}{
+		"message": fmt.Sprintf("%d quizzes deleted successfully", len(quizNames)),
+		"deleted": quizNames,
+	}), nil
+}
+
+// ✅ Digest of the matched quiz names, so a token only confirms the set that
+// was previewed
+func bulkDeleteToken(quizNames []string) string {
+	digest := sha256.Sum256([]byte(strings.Join(quizNames, "\n")))
+	return hex.EncodeToString(digest[:])
+}
+
+// ✅ Append to the content change feed in the transaction making the change.
+// The quiz's category is looked up at the time of the change, so record
+// deletions before deleting
+func recordContentChange(tx *sql.Tx, changeType, quizName, detail, changedBy string) error {
+	_, err := tx.Exec(`
+		INSERT INTO content_changes (change_type, quiz_name, category, detail, changed_by)
+		VALUES ($1, $2, COALESCE((SELECT category FROM quiz_questions WHERE quiz_name = $2), ''), NULLIF($3, ''), $4)`,
+		changeType, quizName, detail, strings.ToLower(changedBy))
+	return err
+}
+
+// ✅ Handle Content Changes (teacher/admin/super): the change feed after ?since=,
+// either the nextSince cursor of a previous page or an RFC 3339 time
+func handleContentChanges(request events.LambdaFunctionURLRequest) (events.LambdaFunctionURLResponse, error) {
+	if request.RequestContext.HTTP.Method != "GET" {
+		return createErrorResponse(405, "Method not allowed"), nil
+	}
+
+	userEmail, err := getAuthenticatedEmail(request)
+	if err != nil {
+		log.Printf("❌ Token verification failed: %v", err)
+		return createErrorResponse(401, "Unauthorized"), nil
+	}
+
+	queryParams := request.QueryStringParameters
+	where := "id > $1"
+	var since interface{} = int64(0)
+	if value := queryParams["since"]; value != "" {
+		if cursor, err := strconv.ParseInt(value, 10, 64); err == nil && cursor >= 0 {
+			since = cursor
+		} else if sinceTime, err := time.Parse(time.RFC3339, value); err == nil {
+			where = "created_at > $1"
+			since = sinceTime
+		} else {
+			return createErrorResponse(400, "Invalid 'since', expected a cursor or an RFC 3339 time"), nil
+		}
+	}
+	limit := 500
+	if value := queryParams["limit"]; value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit <= 0 || limit > 1000 {
+			return createErrorResponse(400, "Invalid 'limit' (1-1000)"), nil
+		}
+	}
+
+	db, err := connectDB()
+	if err != nil {
+		log.Println("❌ Database connection error:", err)
+		return createErrorResponse(500, "Database connection failed"), nil
+	}
+	defer db.Close()
+
+	userRole, err := getUserRole(db, userEmail)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("❌ Failed to get user role: %v", err)
+		return createErrorResponse(500, "Failed to verify user permissions"), nil
+	}
+	if !hasRole(userRole, "teacher", "admin", "super") {
+		return createErrorResponse(403, "Only 'teacher', 'admin' or 'super' role can read content changes"), nil
+	}
+
+	// One extra row tells whether another page follows
+	rows, err := db.Query(`
+		SELECT id, change_type, quiz_name, category, COALESCE(detail, ''), changed_by, created_at
+		FROM content_changes WHERE `+where+`
+		ORDER BY id
+		LIMIT $2`, since, limit+1)
+	if err != nil {
+		log.Printf("❌ Failed to fetch content changes: %v", err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+	defer rows.Close()
+
+	changes := []ContentChange{}
+	for rows.Next() {
+		var change ContentChange
+		if err := rows.Scan(&change.ID, &change.Type, &change.QuizName, &change.Category, &change.Detail,
+			&change.ChangedBy, &change.CreatedAt); err != nil {
+			log.Printf("❌ Failed to read content changes: %v", err)
+			return createErrorResponse(500, "Internal server error"), nil
+		}
+		changes = append(changes, change)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("❌ Failed to read content changes: %v", err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+
+	hasMore := len(changes) > limit
+	if hasMore {
+		changes = changes[:limit]
+	}
+	nextSince := since
+	if len(changes) > 0 {
+		nextSince = changes[len(changes)-1].ID
+	}
+
+	return createJSONResponse(200, map[string]interface{}{
+		"changes":   changes,
+		"nextSince": nextSince,
+		"hasMore":   hasMore,
+	}), nil
+}
+
+// Helper function to run a single-column text query
+func queryStrings(tx *sql.Tx, query string, args ...interface{}) ([]string, error) {
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	values := []string{}
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, rows.Err()
+}
+
+// ✅ Handle Failover Status (admin/super): which region and database this Lambda is bound to
+func handleFailoverStatus(request events.LambdaFunctionURLRequest) (events.LambdaFunctionURLResponse, error) {
+	if request.RequestContext.HTTP.Method != "GET" {
+		return createErrorResponse(405, "Method not allowed"), nil
+	}
+
+	userEmail, err := getAuthenticatedEmail(request)
+	if err != nil {
+		log.Printf("❌ Token verification failed: %v", err)
+		return createErrorResponse(401, "Unauthorized"), nil
+	}
+
+	db, err := connectDB()
+	if err != nil {
+		log.Println("❌ Database connection error:", err)
+		return createErrorResponse(500, "Database connection failed"), nil
+	}
+	defer db.Close()
+
+	userRole, err := getUserRole(db, userEmail)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("❌ Failed to get user role: %v", err)
+		return createErrorResponse(500, "Failed to verify user permissions"), nil
+	}
+	if !hasRole(userRole, "admin", "super") {
+		return createErrorResponse(403, "Only 'admin' or 'super' role can view failover status"), nil
+	}
+
+	// A standby that has not been promoted yet is still in recovery (read-only)
+	var inRecovery bool
+	if err := db.QueryRow(`SELECT pg_is_in_recovery()`).Scan(&inRecovery); err != nil {
+		log.Printf("❌ Failed to check recovery status: %v", err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+
+	host, port := activeDBHost()
+	return createJSONResponse(200, map[string]interface{}{
+		"region":            os.Getenv("AWS_REGION"),
+		"dbTarget":          activeDBTarget(),
+		"dbHost":            host,
+		"dbPort":            port,
+		"dbReadOnly":        inRecovery,
+		"standbyConfigured": DBStandbyHost != "",
+	}), nil
+}
+
+// ✅ Handle Quiz Upload
+func handleQuizUpload(request events.LambdaFunctionURLRequest) (response events.LambdaFunctionURLResponse, err error) {
+	queryParams := request.QueryStringParameters
+	category := queryParams["category"]
+	durationStr := queryParams["duration"]
+	quizName := queryParams["quizName"]
+	instructions := queryParams["instructions"]
+	publishAt, closeAt, windowErr := parsePublishWindow(queryParams["publishAt"], queryParams["closeAt"])
+	metadata, metadataErr := parseQuizMetadata(queryParams)
+	scoring, scoringErr := parseScoringConfig(queryParams["correctMarks"], queryParams["wrongPenalty"], queryParams["unansweredMarks"])
+	allSheets := queryParams["allSheets"] == "true"
+	sheet := queryParams["sheet"]
+	filePassword := queryParams["filePassword"]
+
+	// ✅ Every upload attempt is recorded in upload_audit with its outcome
+	uploaderEmail, err := getAuthenticatedEmail(request)
+	if err != nil {
+		log.Printf("❌ Token verification failed: %v", err)
+		return createErrorResponse(401, "Unauthorized"), nil
+	}
+	audit := UploadAuditEntry{UploaderEmail: strings.ToLower(uploaderEmail), QuizName: quizName, Category: category}
+	defer func() {
+		recordUploadAudit(audit, response)
+	}()
+
+	// ✅ mode=append merges into the stored quiz instead of replacing it;
+	// mode=bank adds the questions to the question bank instead of a quiz
+	mode := queryParams["mode"]
+	if mode == "" {
+		mode = UploadModeReplace
+	}
+	if mode != UploadModeReplace && mode != UploadModeAppend && mode != UploadModeBank {
+		return createErrorResponse(400, "mode must be 'replace', 'append' or 'bank'"), nil
+	}
+	bank := mode == UploadModeBank
+
+	if category == "" || (durationStr == "" && !bank) {
+		return createErrorResponse(400, "Missing required query parameters"), nil
+	}
+	difficulty := strings.ToLower(queryParams["difficulty"])
+	if difficulty != "" && !containsString(validDifficulties, difficulty) {
+		return createErrorResponse(400, "difficulty must be 'easy', 'medium' or 'hard'"), nil
+	}
+
+	// ✅ Unknown categories make quizzes invisible to students, so reject them
+	if len(validCategories) > 0 && !containsString(validCategories, category) {
+		return createJSONResponse(422, map[string]interface{}{
+			"error":       fmt.Sprintf("Invalid category: %s", category),
+			"allowed":     validCategories,
+			"suggestions": suggestCategories(category),
+		}), nil
+	}
+
+	// ✅ Duplicate questions are rejected unless onDuplicate=skip
+	onDuplicate := queryParams["onDuplicate"]
+	if onDuplicate == "" {
+		onDuplicate = "reject"
+	}
+	if onDuplicate != "reject" && onDuplicate != "skip" {
+		return createErrorResponse(400, "onDuplicate must be 'reject' or 'skip'"), nil
+	}
+
+	// ✅ onError=skip saves the valid rows and reports the invalid ones
+	onError := queryParams["onError"]
+	if onError == "" {
+		onError = "reject"
+	}
+	if onError != "reject" && onError != "skip" {
+		return createErrorResponse(400, "onError must be 'reject' or 'skip'"), nil
+	}
+
+	var duration int
+	if durationStr != "" {
+		duration, err = strconv.Atoi(durationStr)
+		if err != nil {
+			return createErrorResponse(400, "Invalid duration format"), nil
+		}
+	}
+	if windowErr != nil {
+		return createErrorResponse(400, windowErr.Error()), nil
+	}
+	if metadataErr != nil {
+		return createErrorResponse(400, metadataErr.Error()), nil
+	}
+	if scoringErr != nil {
+		return createErrorResponse(400, scoringErr.Error()), nil
+	}
+
+	// ✅ Reject oversized files before decoding them
+	if base64.StdEncoding.DecodedLen(len(request.Body)) > MaxUploadBytes {
+		return createErrorResponse(413, fmt.Sprintf("File exceeds the %d byte upload limit", MaxUploadBytes)), nil
+	}
+
+	// ✅ One upload at a time per user; a double-submitted large file would
+	// otherwise be parsed and saved twice
+	release, acquired := acquireUserLock(audit.UploaderEmail, "upload")
+	if !acquired {
+		return createErrorResponse(409, "Another upload from this account is still in progress"), nil
+	}
+	defer release()
+
+	fileContent, err := base64.StdEncoding.DecodeString(request.Body)
+	if err != nil {
+		return createErrorResponse(400, "Invalid file encoding"), nil
+	}
+	fileHash := sha256.Sum256(fileContent)
+	audit.FileHash = hex.EncodeToString(fileHash[:])
+	processedKey := uploadKey(audit.FileHash, uploadOptions(queryParams, mode, onError, onDuplicate))
+
+	// ✅ CSV exports (e.g. from Google Sheets) share the same row pipeline;
+	// spreadsheets are identified by their magic bytes
+	fileFormat := fileFormatCSV
+	if !strings.HasPrefix(strings.ToLower(getHeader(request, "Content-Type")), "text/csv") {
+		fileFormat = detectFileFormat(fileContent)
+	}
+	// ✅ Password-protected workbooks are opened with filePassword
+	if fileFormat == fileFormatEncrypted {
+		if filePassword == "" {
+			return createErrorResponse(400, "File is password-protected, please provide 'filePassword'"), nil
+		}
+		fileFormat = fileFormatXLSX
+	}
+	switch fileFormat {
+	case fileFormatXLS:
+		return createErrorResponse(415, "Legacy .xls files are not supported, please save the file as .xlsx or .ods"), nil
+	case fileFormatUnknown:
+		return createErrorResponse(415, "Unsupported file format, expected .xlsx, .ods, CSV or .zip"), nil
+	}
+	if allSheets && fileFormat != fileFormatXLSX {
+		return createErrorResponse(400, "allSheets is only supported for .xlsx uploads"), nil
+	}
+	if sheet != "" && (allSheets || fileFormat != fileFormatXLSX) {
+		return createErrorResponse(400, "sheet is only supported for single-quiz .xlsx uploads"), nil
+	}
+
+	// ✅ quizName is taken from the sheet names in multi-sheet mode and from
+	// the file names in a ZIP archive
+	multiQuiz := allSheets || fileFormat == fileFormatZIP
+	if quizName == "" && !multiQuiz && !bank {
+		return createErrorResponse(400, "Missing required query parameters"), nil
+	}
+
+	// ✅ Retries of an identical upload are acknowledged without re-processing
+	// (bank uploads are idempotent by question hash anyway)
+	if queryParams["force"] != "true" && !bank {
+		checkName := quizName
+		if multiQuiz {
+			checkName = ""
+		}
+		processed, err := isAlreadyProcessed(processedKey, checkName, category, duration)
+		if err != nil {
+			log.Printf("⚠️ Failed to check for duplicate upload: %v", err)
+		} else if processed {
+			log.Printf("♻️ File %s already processed, skipping", audit.FileHash)
+			return createSuccessResponse("File already processed"), nil
+		}
+	}
+
+	var quizzes []QuizData
+	var quizData QuizData
+	var fileResults []ZipFileResult
+	switch {
+	case allSheets:
+		quizzes, err = processExcelAllSheets(fileContent, category, duration, filePassword)
+	case fileFormat == fileFormatZIP:
+		quizzes, fileResults, err = processZipArchive(fileContent, category, duration)
+		if err == nil && zipHasFailures(fileResults) && (onError == "reject" || len(quizzes) == 0) {
+			return createJSONResponse(422, map[string]interface{}{
+				"error": "Some files in the archive could not be processed",
+				"files": fileResults,
+			}), nil
+		}
+	case fileFormat == fileFormatCSV:
+		quizData, err = processCSV(fileContent, category, duration, quizName)
+		quizzes = []QuizData{quizData}
+	case fileFormat == fileFormatODS:
+		quizData, err = processODS(fileContent, category, duration, quizName)
+		quizzes = []QuizData{quizData}
+	default:
+		quizData, err = processExcel(fileContent, category, duration, quizName, sheet, filePassword)
+		quizzes = []QuizData{quizData}
+	}
+	var validationErr *UploadValidationError
+	var skippedRows []RowError
+	if errors.As(err, &validationErr) && onError == "skip" && hasQuestions(quizzes) {
+		skippedRows = validationErr.Rows
+		err = nil
+	}
+	if err != nil {
+		log.Printf("❌ Failed to process upload: %v", err)
+		return uploadErrorResponse(err), nil
+	}
+	for i := range quizzes {
+		quizzes[i].FileHash = processedKey
+		if instructions != "" {
+			quizzes[i].Instructions = instructions
+		}
+		quizzes[i].PublishAt = publishAt
+		quizzes[i].CloseAt = closeAt
+		quizzes[i].Metadata = metadata
+		quizzes[i].Scoring = scoring
+	}
+
+	// ✅ Detect copy-pasted questions within each quiz
+	var duplicates []RowError
+	for i := range quizzes {
+		unique, quizDuplicates := dedupeQuestions(quizzes[i].Questions)
+		for _, duplicate := range quizDuplicates {
+			if multiQuiz {
+				duplicate.Sheet = quizzes[i].QuizName
+			}
+			duplicates = append(duplicates, duplicate)
+		}
+		quizzes[i].Questions = unique
+	}
+	if len(duplicates) > 0 && onDuplicate == "reject" {
+		return createJSONResponse(422, map[string]interface{}{
+			"error": "Uploaded file contains duplicate questions",
+			"rows":  duplicates,
+		}), nil
+	}
+
+	var quizNames []string
+	for _, quiz := range quizzes {
+		quizNames = append(quizNames, quiz.QuizName)
+		audit.RowCount += len(quiz.Questions)
+	}
+	audit.QuizName = strings.Join(quizNames, ", ")
+
+	// ✅ Institute-specific rules from the enabled upload hooks
+	hookContext := UploadContext{UploaderEmail: audit.UploaderEmail, Category: category, Mode: mode}
+	if hookErrors := runPreValidateHooks(hookContext, quizzes, multiQuiz); len(hookErrors) > 0 {
+		return createJSONResponse(422, map[string]interface{}{
+			"error": "Upload rejected by validation rules",
+			"rows":  hookErrors,
+		}), nil
+	}
+
+	if bank {
+		added, updated, err := saveToQuestionBank(audit.UploaderEmail, category, metadata.Topic, difficulty, quizzes)
+		if err != nil {
+			log.Printf("❌ Failed to save question bank: %v", err)
+			return createErrorResponse(500, "Failed to save to database"), nil
+		}
+		runPostSaveHooks(hookContext, quizzes)
+		result := map[string]interface{}{
+			"message": fmt.Sprintf("%d question(s) added to the question bank", added),
+			"added":   added,
+			"updated": updated,
+		}
+		if len(duplicates) > 0 {
+			result["duplicatesRemoved"] = duplicates
+		}
+		if len(skippedRows) > 0 {
+			result["skippedRows"] = skippedRows
+		}
+		return createJSONResponse(200, result), nil
+	}
+
+	err = saveToPostgres(mode, audit.UploaderEmail, quizzes...)
+	if err != nil {
+		log.Printf("❌ Failed to save quizzes: %v", err)
+		return createErrorResponse(500, "Failed to save to database"), nil
+	}
+	runPostSaveHooks(hookContext, quizzes)
+
+	message := "Quiz uploaded successfully"
+	if multiQuiz {
+		message = fmt.Sprintf("%d quizzes uploaded successfully", len(quizzes))
+	}
+	if len(duplicates) > 0 || len(skippedRows) > 0 || fileResults != nil {
+		result := map[string]interface{}{"message": message}
+		if len(duplicates) > 0 {
+			result["duplicatesRemoved"] = duplicates
+		}
+		if len(skippedRows) > 0 {
+			result["skippedRows"] = skippedRows
+		}
+		if fileResults != nil {
+			result["files"] = fileResults
+		}
+		return createJSONResponse(200, result), nil
+	}
+	return createSuccessResponse(message), nil
+}
+
+// ✅ Take a per-user Postgres advisory lock for an operation. The lock lives on
+// a dedicated connection until release is called, and Postgres drops it if the
+// Lambda dies mid-request. Lock errors are logged and the work goes ahead.
+func acquireUserLock(email, operation string) (release func(), acquired bool) {
+	noop := func() {}
+
+	db, err := connectDB()
+	if err != nil {
+		log.Printf("⚠️ Failed to take %s lock: %v", operation, err)
+		return noop, true
+	}
+
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		db.Close()
+		log.Printf("⚠️ Failed to take %s lock: %v", operation, err)
+		return noop, true
+	}
+
+	key := operation + ":" + strings.ToLower(email)
+	err = conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock(hashtext($1))", key).Scan(&acquired)
+	if err != nil || !acquired {
+		conn.Close()
+		db.Close()
+		if err != nil {
+			log.Printf("⚠️ Failed to take %s lock: %v", operation, err)
+			return noop, true
+		}
+		log.Printf("🔒 %s already in progress for %s", operation, email)
+		return noop, false
+	}
+
+	return func() {
+		if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock(hashtext($1))", key); err != nil {
+			log.Printf("⚠️ Failed to release %s lock: %v", operation, err)
+		}
+		conn.Close()
+		db.Close()
+	}, true
+}
+
+// UploadContext describes the upload a hook is running for
+type UploadContext struct {
+	UploaderEmail string
+	Category      string
+	Mode          string
+}
+
+// PreValidateHook checks parsed quizzes before they are saved; any returned
+// row errors reject the whole upload
+type PreValidateHook interface {
+	PreValidate(ctx UploadContext, quiz QuizData) []RowError
+}
+
+// PostSaveHook runs after the quizzes are committed; errors are only logged
+// because the upload has already succeeded
+type PostSaveHook interface {
+	PostSave(ctx UploadContext, quizzes []QuizData) error
+}
+
+// ✅ Upload Hooks: registered in code by name and switched on with the
+// UPLOAD_HOOKS env var (comma-separated names). A hook implementing both
+// interfaces is registered under the same name in both maps.
+var preValidateHooks = map[string]PreValidateHook{
+	"quiz-name-pattern": quizNamePatternHook{},
+}
+
+var postSaveHooks = map[string]PostSaveHook{}
+
+// Hooks switched on by UPLOAD_HOOKS, in its order; set by initUploadHooks
+var (
+	enabledPreValidateHooks []PreValidateHook
+	enabledPostSaveHooks    []PostSaveHook
+)
+
+// ✅ Resolve UPLOAD_HOOKS against the registered hooks; an unknown name is a
+// deployment mistake, so it stops the function from starting
+func initUploadHooks() error {
+	var preValidate []PreValidateHook
+	var postSave []PostSaveHook
+	for _, name := range getEnvList("UPLOAD_HOOKS") {
+		preValidateHook, isPreValidate := preValidateHooks[name]
+		postSaveHook, isPostSave := postSaveHooks[name]
+		if !isPreValidate && !isPostSave {
+			return fmt.Errorf("unknown upload hook %q in UPLOAD_HOOKS", name)
+		}
+		if isPreValidate {
+			preValidate = append(preValidate, preValidateHook)
+		}
+		if isPostSave {
+			postSave = append(postSave, postSaveHook)
+		}
+	}
+	enabledPreValidateHooks, enabledPostSaveHooks = preValidate, postSave
+	return nil
+}
+
+// ✅ Run every enabled pre-validate hook over every quiz
+func runPreValidateHooks(ctx UploadContext, quizzes []QuizData, multiQuiz bool) []RowError {
+	var rowErrors []RowError
+	for _, hook := range enabledPreValidateHooks {
+		for _, quiz := range quizzes {
+			for _, rowErr := range hook.PreValidate(ctx, quiz) {
+				if multiQuiz && rowErr.Sheet == "" {
+					rowErr.Sheet = quiz.QuizName
+				}
+				rowErrors = append(rowErrors, rowErr)
+			}
+		}
+	}
+	return rowErrors
+}
+
+// ✅ Run every enabled post-save hook
+func runPostSaveHooks(ctx UploadContext, quizzes []QuizData) {
+	for _, hook := range enabledPostSaveHooks {
+		if err := hook.PostSave(ctx, quizzes); err != nil {
+			log.Printf("⚠️ Upload hook %T failed: %v", hook, err)
+		}
+	}
+}
+
+// quizNamePatternHook enforces a quiz naming convention given as a regular
+// expression in QUIZ_NAME_PATTERN (e.g. ^[A-Z]+-\d{4}-\d{2}-\d{2}$)
+type quizNamePatternHook struct{}
+
+func (quizNamePatternHook) PreValidate(ctx UploadContext, quiz QuizData) []RowError {
+	pattern := os.Getenv("QUIZ_NAME_PATTERN")
+	if pattern == "" || ctx.Mode == UploadModeBank {
+		return nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		log.Printf("⚠️ Ignoring invalid QUIZ_NAME_PATTERN: %v", err)
+		return nil
+	}
+	if !re.MatchString(quiz.QuizName) {
+		return []RowError{{Column: "quizName", Reason: fmt.Sprintf("quiz name %q does not match the required pattern %s", quiz.QuizName, pattern)}}
+	}
+	return nil
+}
+
+// Helper function to collect the upload options that change what gets saved;
+// defaults are left out so they key like an upload without options
+func uploadOptions(queryParams map[string]string, mode, onError, onDuplicate string) map[string]string {
+	options := make(map[string]string)
+	for _, name := range []string{
+		"sheet", "allSheets", "instructions", "publishAt", "closeAt",
+		"topic", "chapter", "author", "examYear", "tags",
+		"correctMarks", "wrongPenalty", "unansweredMarks",
+	} {
+		options[name] = queryParams[name]
+	}
+	if mode != UploadModeReplace {
+		options["mode"] = mode
+	}
+	if onError != "reject" {
+		options["onError"] = onError
+	}
+	if onDuplicate != "reject" {
+		options["onDuplicate"] = onDuplicate
+	}
+	return options
+}
+
+// ✅ Key an upload by its file hash and the options that change what gets saved,
+// so re-uploading a file with e.g. another sheet is processed again. Empty
+// options are left out, keeping the plain file hash when none are given
+func uploadKey(fileHash string, options map[string]string) string {
+	names := make([]string, 0, len(options))
+	for name, value := range options {
+		if value != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return fileHash
+	}
+	sort.Strings(names)
+
+	var key strings.Builder
+	key.WriteString(fileHash)
+	for _, name := range names {
+		fmt.Fprintf(&key, "\n%s=%s", name, options[name])
+	}
+	digest := sha256.Sum256([]byte(key.String()))
+	return hex.EncodeToString(digest[:])
+}
+
+// ✅ Check whether this exact file was already saved with the same settings;
+// with an empty quizName (allSheets) any quiz from the file counts
+func isAlreadyProcessed(fileHash, quizName, category string, duration int) (bool, error) {
+	db, err := connectDB()
+	if err != nil {
+		return false, err
+	}
+	defer db.Close()
+
+	var exists bool
+	err = db.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM quiz_questions
+			WHERE file_hash = $1 AND category = $2 AND duration = $3 AND ($4 = '' OR quiz_name = $4)
+				AND deleted_at IS NULL
+		)`, fileHash, category, duration, quizName).Scan(&exists)
+	return exists, err
+}
+
+// ✅ Record an upload attempt; failures are logged but never fail the request
+func recordUploadAudit(audit UploadAuditEntry, response events.LambdaFunctionURLResponse) {
+	audit.StatusCode = response.StatusCode
+	audit.Outcome = "succeeded"
+	if response.StatusCode >= 400 {
+		audit.Outcome = "failed"
+		audit.Message = response.Body
+		if len(audit.Message) > 2000 {
+			audit.Message = audit.Message[:2000]
+		}
+	}
+
+	db, err := connectDB()
+	if err != nil {
+		log.Printf("⚠️ Failed to record upload audit: %v", err)
+		return
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`
+		INSERT INTO upload_audit (uploader_email, quiz_name, category, row_count, file_hash, status_code, outcome, message)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''))`,
+		audit.UploaderEmail, audit.QuizName, audit.Category, audit.RowCount, audit.FileHash,
+		audit.StatusCode, audit.Outcome, audit.Message)
+	if err != nil {
+		log.Printf("⚠️ Failed to record upload audit: %v", err)
+	}
+}
+
+// ✅ Handle Upload History (admin/super, filters: uploader, quizName, outcome, from, to, limit)
+func handleUploadHistory(request events.LambdaFunctionURLRequest) (events.LambdaFunctionURLResponse, error) {
+	if request.RequestContext.HTTP.Method != "GET" {
+		return createErrorResponse(405, "Method not allowed"), nil
+	}
+
+	userEmail, err := getAuthenticatedEmail(request)
+	if err != nil {
+		log.Printf("❌ Token verification failed: %v", err)
+		return createErrorResponse(401, "Unauthorized"), nil
+	}
+
+	db, err := connectDB()
+	if err != nil {
+		log.Println("❌ Database connection error:", err)
+		return createErrorResponse(500, "Database connection failed"), nil
+	}
+	defer db.Close()
+
+	userRole, err := getUserRole(db, userEmail)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("❌ Failed to get user role: %v", err)
+		return createErrorResponse(500, "Failed to verify user permissions"), nil
+	}
+	if !hasRole(userRole, "admin", "super") {
+		return createErrorResponse(403, "Only 'admin' or 'super' role can view upload history"), nil
+	}
+
+	// ✅ Build Dynamic Filters
+	queryParams := request.QueryStringParameters
+	query := `
+		SELECT id, uploader_email, quiz_name, category, row_count, file_hash, status_code, outcome,
+			COALESCE(message, ''), created_at
+		FROM upload_audit WHERE 1 = 1`
+	params := []interface{}{}
+	if uploader := queryParams["uploader"]; uploader != "" {
+		params = append(params, strings.ToLower(uploader))
+		query += fmt.Sprintf(" AND uploader_email = $%d", len(params))
+	}
+	if quizName := queryParams["quizName"]; quizName != "" {
+		params = append(params, "%"+escapeLike(quizName)+"%")
+		query += fmt.Sprintf(" AND quiz_name ILIKE $%d", len(params))
+	}
+	if outcome := queryParams["outcome"]; outcome != "" {
+		params = append(params, outcome)
+		query += fmt.Sprintf(" AND outcome = $%d", len(params))
+	}
+	for _, bound := range []struct{ param, condition string }{{"from", ">="}, {"to", "<"}} {
+		value := queryParams[bound.param]
+		if value == "" {
+			continue
+		}
+		date, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return createErrorResponse(400, fmt.Sprintf("Invalid '%s', expected YYYY-MM-DD", bound.param)), nil
+		}
+		if bound.param == "to" {
+			// Make the end date inclusive
+			date = date.AddDate(0, 0, 1)
+		}
+		params = append(params, date)
+		query += fmt.Sprintf(" AND created_at %s $%d", bound.condition, len(params))
+	}
+
+	limit := 100
+	if limitStr := queryParams["limit"]; limitStr != "" {
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 || limit > 500 {
+			return createErrorResponse(400, "limit must be between 1 and 500"), nil
+		}
+	}
+	params = append(params, limit)
+	query += fmt.Sprintf(" ORDER BY created_at DESC LIMIT $%d", len(params))
+
+	rows, err := db.Query(query, params...)
+	if err != nil {
+		log.Printf("❌ Failed to fetch upload history: %v", err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+	defer rows.Close()
+
+	entries := []UploadAuditEntry{}
+	for rows.Next() {
+		var entry UploadAuditEntry
+		err := rows.Scan(&entry.ID, &entry.UploaderEmail, &entry.QuizName, &entry.Category, &entry.RowCount,
+			&entry.FileHash, &entry.StatusCode, &entry.Outcome, &entry.Message, &entry.CreatedAt)
+		if err != nil {
+			log.Printf("❌ Failed to read upload history: %v", err)
+			return createErrorResponse(500, "Internal server error"), nil
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("❌ Failed to read upload history: %v", err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+
+	return createJSONResponse(200, entries), nil
+}
+
+// ✅ Handle Quiz Info (metadata shown before starting, never the questions)
+func handleQuizInfo(request events.LambdaFunctionURLRequest) (events.LambdaFunctionURLResponse, error) {
+	if request.RequestContext.HTTP.Method != "GET" {
+		return createErrorResponse(405, "Method not allowed"), nil
+	}
+
+	quizName, err := url.PathUnescape(strings.TrimSuffix(strings.TrimPrefix(request.RawPath, "/quizzes/"), "/info"))
+	if err != nil || quizName == "" {
+		return createErrorResponse(400, "Invalid quiz name"), nil
+	}
+
+	db, err := connectDB()
+	if err != nil {
+		log.Println("❌ Database connection error:", err)
+		return createErrorResponse(500, "Database connection failed"), nil
+	}
+	defer db.Close()
+
+	var info QuizInfo
+	var instructions sql.NullString
+	var correctMarks, wrongPenalty, unansweredMarks sql.NullFloat64
+	err = db.QueryRow(`
+		SELECT quiz_name, category, duration, jsonb_array_length(questions), instructions, publish_at, close_at,
+			correct_marks, wrong_penalty, unanswered_marks
+		FROM quiz_questions WHERE quiz_name = $1 AND status = $2 AND deleted_at IS NULL`, quizName, QuizStatusPublished).
+		Scan(&info.QuizName, &info.Category, &info.Duration, &info.QuestionCount, &instructions, &info.PublishAt, &info.CloseAt,
+			&correctMarks, &wrongPenalty, &unansweredMarks)
+	if errors.Is(err, sql.ErrNoRows) {
+		return createErrorResponse(404, "Quiz not found"), nil
+	}
+	if err != nil {
+		log.Printf("❌ Failed to fetch quiz info for %s: %v", quizName, err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+	info.Instructions = instructions.String
+	info.Scoring = scoringFromColumns(correctMarks, wrongPenalty, unansweredMarks)
+
+	return createJSONResponse(200, info), nil
+}
+
+// ✅ Handle Quiz Preview (teacher/admin/super): the stored questions with answers
+// and explanations inline, for content QA. Nothing is recorded as an attempt.
+// ?difficulty=easy,medium keeps only questions tagged with those levels
+func handleQuizPreview(request events.LambdaFunctionURLRequest) (events.LambdaFunctionURLResponse, error) {
+	if request.RequestContext.HTTP.Method != "GET" {
+		return createErrorResponse(405, "Method not allowed"), nil
+	}
+
+	quizName, err := url.PathUnescape(strings.TrimSuffix(strings.TrimPrefix(request.RawPath, "/admin/quizzes/"), "/preview"))
+	if err != nil || quizName == "" {
+		return createErrorResponse(400, "Invalid quiz name"), nil
+	}
+
+	userEmail, err := getAuthenticatedEmail(request)
+	if err != nil {
+		log.Printf("❌ Token verification failed: %v", err)
+		return createErrorResponse(401, "Unauthorized"), nil
+	}
+
+	difficulties, err := parseDifficultyBand(request.QueryStringParameters["difficulty"])
+	if err != nil {
+		return createErrorResponse(400, err.Error()), nil
+	}
+
+	db, err := connectDB()
+	if err != nil {
+		log.Println("❌ Database connection error:", err)
+		return createErrorResponse(500, "Database connection failed"), nil
+	}
+	defer db.Close()
+
+	userRole, err := getUserRole(db, userEmail)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("❌ Failed to get user role: %v", err)
+		return createErrorResponse(500, "Failed to verify user permissions"), nil
+	}
+	if !hasRole(userRole, "teacher", "admin", "super") {
+		return createErrorResponse(403, "Only 'teacher', 'admin' or 'super' role can preview quizzes"), nil
+	}
+
+	var quiz QuizData
+	var instructions sql.NullString
+	var questionsJSON []byte
+	var correctMarks, wrongPenalty, unansweredMarks sql.NullFloat64
+	err = db.QueryRow(`
+		SELECT quiz_name, category, duration, instructions, questions, publish_at, close_at,
+			correct_marks, wrong_penalty, unanswered_marks
+		FROM quiz_questions WHERE quiz_name = $1 AND deleted_at IS NULL`, quizName).
+		Scan(&quiz.QuizName, &quiz.Category, &quiz.Duration, &instructions, &questionsJSON, &quiz.PublishAt, &quiz.CloseAt,
+			&correctMarks, &wrongPenalty, &unansweredMarks)
+	if errors.Is(err, sql.ErrNoRows) {
+		return createErrorResponse(404, "Quiz not found"), nil
+	}
+	if err != nil {
+		log.Printf("❌ Failed to fetch quiz %s for preview: %v", quizName, err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+	quiz.Instructions = instructions.String
+	quiz.Scoring = scoringFromColumns(correctMarks, wrongPenalty, unansweredMarks)
+	if err := json.Unmarshal(questionsJSON, &quiz.Questions); err != nil {
+		log.Printf("❌ Failed to decode questions of %s: %v", quizName, err)
+		return createErrorResponse(500, "Internal server error"), nil
+	}
+	if len(difficulties) > 0 {
+		quiz.Questions = filterByDifficulty(quiz.Questions, difficulties)
+	}
+
+	return createJSONResponse(200, quiz), nil
+}
+
+// ✅ Parse a comma-separated difficulty band (e.g. "medium,hard")
+func parseDifficultyBand(value string) ([]string, error) {
+	var band []string
+	for _, difficulty := range strings.Split(value, ",") {
+		difficulty = strings.ToLower(strings.TrimSpace(difficulty))
+		if difficulty == "" {
+			continue
+		}
+		if !containsString(validDifficulties, difficulty) {
+			return nil, errors.New("Invalid 'difficulty' (use easy, medium or hard)")
+		}
+		band = append(band, difficulty)
+	}
+	return band, nil
+}
+
+// Helper function to keep the questions in a difficulty band; untagged
+// questions are left out
+func filterByDifficulty(questions []Question, band []string) []Question {
+	filtered := []Question{}
+	for _, question := range questions {
+		if containsString(band, question.Difficulty) {
+			filtered = append(filtered, question)
+		}
+	}
+	return filtered
+}
+
+// ✅ Handle Template Download
+func handleTemplateDownload(request events.LambdaFunctionURLRequest) (events.LambdaFunctionURLResponse, error) {
+	if request.RequestContext.HTTP.Method != "GET" {
+		return createErrorResponse(405, "Method not allowed"), nil
+	}
+
+	buf, err := generateQuestionTemplate()
+	if err != nil {
+		log.Printf("❌ Failed to generate template: %v", err)
+		return createErrorResponse(500, "Failed to generate template"), nil
+	}
+
+	headers := getCORSHeaders()
+	headers["Content-Type"] = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	headers["Content-Disposition"] = `attachment; filename="questions_template.xlsx"`
+
+	return events.LambdaFunctionURLResponse{
+		StatusCode:      200,
+		Headers:         headers,
+		Body:            base64.StdEncoding.EncodeToString(buf.Bytes()),
+		IsBase64Encoded: true,
+	}, nil
+}
+
+// ✅ Build the upload template workbook with headers, examples and dropdowns
+func generateQuestionTemplate() (*bytes.Buffer, error) {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	sheetName := "Questions"
+	if err := f.SetSheetName(f.GetSheetName(0), sheetName); err != nil {
+		return nil, err
+	}
+
+	rows := [][]interface{}{
+		{"Question", "CorrectAnswer", "IncorrectAnswers", "Explanation", "Type", "Marks", "TimeSeconds", "ReferenceURL", "Difficulty"},
+		{"What is the chemical formula of water?", "H2O", "CO2, O2, H2O2", "Water is two hydrogen atoms bonded to one oxygen atom.", "MCQ", 4, 60, "https://en.wikipedia.org/wiki/Properties_of_water", "easy"},
+		{"Which of these are prime numbers?", "2, 3", "4, 9", "2 and 3 have no divisors other than 1 and themselves.", "Multi-Select", 4, 90, "", "medium"},
+		{"The Earth revolves around the Sun.", "True", "False", "The Earth completes one revolution every 365.25 days.", "True/False", 1, 30, "", "easy"},
+		{"What is 12 x 12?", "144", "", "12 x 12 = 144", "Numeric"},
+	}
+	for i, row := range rows {
+		cell, err := excelize.CoordinatesToCellName(1, i+1)
+		if err != nil {
+			return nil, err
+		}
+		if err := f.SetSheetRow(sheetName, cell, &row); err != nil {
+			return nil, err
+		}
+	}
+
+	headerStyle, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		return nil, err
+	}
+	if err := f.SetRowStyle(sheetName, 1, 1, headerStyle); err != nil {
+		return nil, err
+	}
+	if err := f.SetColWidth(sheetName, "A", "D", 40); err != nil {
+		return nil, err
+	}
+
+	typeValidation := excelize.NewDataValidation(true)
+	typeValidation.Sqref = "E2:E1000"
+	if err := typeValidation.SetDropList([]string{"MCQ", "Multi-Select", "True/False", "Numeric"}); err != nil {
+		return nil, err
+	}
+	if err := f.AddDataValidation(sheetName, typeValidation); err != nil {
+		return nil, err
+	}
+
+	difficultyValidation := excelize.NewDataValidation(true)
+	difficultyValidation.Sqref = "I2:I1000"
+	if err := difficultyValidation.SetDropList(validDifficulties); err != nil {
+		return nil, err
+	}
+	if err := f.AddDataValidation(sheetName, difficultyValidation); err != nil {
+		return nil, err
+	}
+
+	return f.WriteToBuffer()
+}
+
+// ✅ Process one sheet of an .xlsx workbook; sheet selects it by name or
+// 0-based index, otherwise the first sheet that isn't the Instructions sheet is used.
+// password opens protected workbooks
+func processExcel(fileBytes []byte, category string, duration int, quizName string, sheet string, password string) (QuizData, error) {
+	f, err := excelize.OpenReader(bytes.NewReader(fileBytes), excelize.Options{Password: password})
+	if err != nil {
+		return QuizData{}, err
+	}
+
+	sheetName, err := selectSheet(f.GetSheetList(), sheet)
+	if err != nil {
+		return QuizData{}, err
+	}
+
+	// Row validation errors still return the quiz built from the valid rows
+	questions, parseErr := parseSheet(f, sheetName)
+	if parseErr != nil && !isRowValidationError(parseErr) {
+		return QuizData{}, parseErr
+	}
+
+	instructions, err := readInstructionsSheet(f)
+	if err != nil {
+		return QuizData{}, err
+	}
+
+	return QuizData{QuizName: quizName, Duration: duration, Category: category, Instructions: instructions, Questions: questions}, parseErr
+}
+
+// ✅ Resolve the sheet to import; an exact name wins over an index so a
+// sheet called "2" can still be chosen
+func selectSheet(sheets []string, sheet string) (string, error) {
+	sheet = strings.TrimSpace(sheet)
+	if sheet == "" {
+		for _, name := range sheets {
+			if !strings.EqualFold(name, instructionsSheetName) {
+				return name, nil
+			}
+		}
+		return "", errors.New("workbook contains no sheets")
+	}
+
+	for _, name := range sheets {
+		if strings.EqualFold(strings.TrimSpace(name), sheet) {
+			return name, nil
+		}
+	}
+	if index, err := strconv.Atoi(sheet); err == nil && index >= 0 && index < len(sheets) {
+		return sheets[index], nil
+	}
+	return "", &SheetNotFoundError{Sheet: sheet, Available: sheets}
+}
+
+// Name of the optional sheet holding syllabus / marking scheme / rules
+const instructionsSheetName = "Instructions"
+
+// ✅ Read the Instructions sheet (if any) as plain text, one line per row
+func readInstructionsSheet(f *excelize.File) (string, error) {
+	for _, name := range f.GetSheetList() {
+		if !strings.EqualFold(name, instructionsSheetName) {
+			continue
+		}
+
+		rows, err := f.GetRows(name)
+		if err != nil {
+			return "", err
+		}
+
+		var lines []string
+		for _, row := range rows {
+			var cells []string
+			for _, cell := range row {
+				if cell = strings.TrimSpace(cell); cell != "" {
+					cells = append(cells, cell)
+				}
+			}
+			if len(cells) > 0 {
+				lines = append(lines, strings.Join(cells, " "))
+			}
+		}
+		return strings.Join(lines, "\n"), nil
+	}
+	return "", nil
+}
+
+// ✅ Process a CSV file with the same header layout as the Excel template
+func processCSV(fileBytes []byte, category string, duration int, quizName string) (QuizData, error) {
+	// Excel adds a UTF-8 BOM to CSV exports, which would corrupt the first header
+	fileBytes = bytes.TrimPrefix(fileBytes, []byte("\xef\xbb\xbf"))
+
+	reader := csv.NewReader(bytes.NewReader(fileBytes))
+	reader.FieldsPerRecord = -1
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return QuizData{}, fmt.Errorf("invalid CSV: %w", err)
+	}
+
+	questions, err := parseQuestionRows(rows)
+	if err != nil && !isRowValidationError(err) {
+		return QuizData{}, err
+	}
+
+	return QuizData{QuizName: quizName, Duration: duration, Category: category, Questions: questions}, err
+}
+
+// ✅ Upload File Formats
+const (
+	fileFormatXLSX      = "xlsx"
+	fileFormatODS       = "ods"
+	fileFormatXLS       = "xls"
+	fileFormatCSV       = "csv"
+	fileFormatZIP       = "zip"
+	fileFormatEncrypted = "encrypted"
+	fileFormatUnknown   = "unknown"
+)
+
+// ✅ Detect the spreadsheet format from the file's magic bytes
+func detectFileFormat(fileBytes []byte) string {
+	switch {
+	case bytes.HasPrefix(fileBytes, []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}):
+		// OLE2 compound document: an encrypted OOXML workbook carries an
+		// EncryptionInfo stream (names are UTF-16LE), anything else is BIFF .xls
+		if bytes.Contains(fileBytes, []byte("E\x00n\x00c\x00r\x00y\x00p\x00t\x00i\x00o\x00n\x00I\x00n\x00f\x00o\x00")) {
+			return fileFormatEncrypted
+		}
+		return fileFormatXLS
+	case bytes.HasPrefix(fileBytes, []byte("PK\x03\x04")):
+		// ODS stores an uncompressed "mimetype" entry first in the zip
+		if bytes.Contains(fileBytes[:min(len(fileBytes), 128)], []byte("application/vnd.oasis.opendocument.spreadsheet")) {
+			return fileFormatODS
+		}
+		// Every OOXML package has a [Content_Types].xml entry; any other zip
+		// is treated as an archive of workbooks
+		zipReader, err := zip.NewReader(bytes.NewReader(fileBytes), int64(len(fileBytes)))
+		if err != nil {
+			return fileFormatXLSX
+		}
+		for _, entry := range zipReader.File {
+			if entry.Name == "[Content_Types].xml" {
+				return fileFormatXLSX
+			}
+		}
+		return fileFormatZIP
+	default:
+		return fileFormatUnknown
+	}
+}
+
+// ZipFileResult reports how one workbook inside an uploaded ZIP archive was processed
+type ZipFileResult struct {
+	File          string     `json:"file"`
+	QuizName      string     `json:"quizName,omitempty"`
+	QuestionCount int        `json:"questionCount"`
+	Error         string     `json:"error,omitempty"`
+	Rows          []RowError `json:"rows,omitempty"`
+}
+
+// Upper bound on workbooks in one archive
+const maxZipEntries = 100
+
+// ✅ Process every workbook in a ZIP archive as its own quiz, named after the
+// file. Results are returned for every entry so one upload reports all problems
+func processZipArchive(fileBytes []byte, category string, duration int) ([]QuizData, []ZipFileResult, error) {
+	zipReader, err := zip.NewReader(bytes.NewReader(fileBytes), int64(len(fileBytes)))
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid ZIP archive: %w", err)
+	}
+
+	var quizzes []QuizData
+	results := []ZipFileResult{}
+	seenNames := make(map[string]string)
+	for _, entry := range zipReader.File {
+		baseName := path.Base(entry.Name)
+		// Skip folders and macOS / hidden metadata files
+		if entry.FileInfo().IsDir() || strings.HasPrefix(entry.Name, "__MACOSX/") || strings.HasPrefix(baseName, ".") {
+			continue
+		}
+		if len(results) == maxZipEntries {
+			return nil, nil, &UploadLimitError{Message: fmt.Sprintf("Archive exceeds the %d file limit", maxZipEntries)}
+		}
+
+		quizName := strings.TrimSpace(strings.TrimSuffix(baseName, path.Ext(baseName)))
+		result := ZipFileResult{File: entry.Name, QuizName: quizName}
+		if previous, exists := seenNames[strings.ToLower(quizName)]; exists {
+			result.Error = fmt.Sprintf("quiz name %s is also used by %s", quizName, previous)
+			results = append(results, result)
+			continue
+		}
+		seenNames[strings.ToLower(quizName)] = entry.Name
+
+		quiz, err := processZipEntry(entry, category, duration, quizName)
+		var validationErr *UploadValidationError
+		var limitErr *UploadLimitError
+		switch {
+		case errors.As(err, &validationErr):
+			result.Error = "file contains invalid rows"
+			result.Rows = validationErr.Rows
+			// The valid rows are only saved with onError=skip
+			result.QuestionCount = len(quiz.Questions)
+			if len(quiz.Questions) > 0 {
+				quizzes = append(quizzes, quiz)
+			}
+		case errors.As(err, &limitErr):
+			result.Error = limitErr.Message
+		case err != nil:
+			log.Printf("⚠️ Failed to process %s from archive: %v", entry.Name, err)
+			result.Error = "failed to read file"
+		default:
+			result.QuestionCount = len(quiz.Questions)
+			quizzes = append(quizzes, quiz)
+		}
+		results = append(results, result)
+	}
+
+	if len(results) == 0 {
+		return nil, nil, &UploadLimitError{Message: "Archive contains no workbooks"}
+	}
+	return quizzes, results, nil
+}
+
+// ✅ Decompress one archive entry and run it through the matching file processor
+func processZipEntry(entry *zip.File, category string, duration int, quizName string) (QuizData, error) {
+	// The declared size can lie, so the read itself is capped too
+	if entry.UncompressedSize64 > uint64(MaxUploadBytes) {
+		return QuizData{}, &UploadLimitError{Message: fmt.Sprintf("file exceeds the %d byte upload limit", MaxUploadBytes)}
+	}
+	reader, err := entry.Open()
+	if err != nil {
+		return QuizData{}, err
+	}
+	defer reader.Close()
+
+	content, err := io.ReadAll(io.LimitReader(reader, int64(MaxUploadBytes)+1))
+	if err != nil {
+		return QuizData{}, err
+	}
+	if len(content) > MaxUploadBytes {
+		return QuizData{}, &UploadLimitError{Message: fmt.Sprintf("file exceeds the %d byte upload limit", MaxUploadBytes)}
+	}
+
+	if strings.EqualFold(path.Ext(entry.Name), ".csv") {
+		return processCSV(content, category, duration, quizName)
+	}
+	switch detectFileFormat(content) {
+	case fileFormatXLSX:
+		return processExcel(content, category, duration, quizName, "", "")
+	case fileFormatODS:
+		return processODS(content, category, duration, quizName)
+	case fileFormatXLS:
+		return QuizData{}, &UploadLimitError{Message: "legacy .xls files are not supported, please save the file as .xlsx or .ods"}
+	case fileFormatEncrypted:
+		return QuizData{}, &UploadLimitError{Message: "password-protected files are not supported inside archives"}
+	default:
+		return QuizData{}, &UploadLimitError{Message: "unsupported file format, expected .xlsx, .ods or CSV"}
+	}
+}
+
+// Helper function to check that there is something left to save
+func hasQuestions(quizzes []QuizData) bool {
+	for _, quiz := range quizzes {
+		if len(quiz.Questions) == 0 {
+			return false
+		}
+	}
+	return len(quizzes) > 0
+}
+
+// Helper function to check whether any archive entry failed
+func zipHasFailures(results []ZipFileResult) bool {
+	for _, result := range results {
+		if result.Error != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// ✅ OpenDocument XML Namespaces
+const (
+	odsOfficeNS = "urn:oasis:names:tc:opendocument:xmlns:office:1.0"
+	odsTableNS  = "urn:oasis:names:tc:opendocument:xmlns:table:1.0"
+	odsTextNS   = "urn:oasis:names:tc:opendocument:xmlns:text:1.0"
+)
+
+// Repeated blank rows/cells are only expanded up to this count; ODS files pad
+// sheets with runs of a million empty rows
+const odsMaxBlankRepeat = 1024
+
+// ✅ Process an OpenDocument spreadsheet (.ods) using its first question sheet
+func processODS(fileBytes []byte, category string, duration int, quizName string) (QuizData, error) {
+	zipReader, err := zip.NewReader(bytes.NewReader(fileBytes), int64(len(fileBytes)))
+	if err != nil {
+		return QuizData{}, fmt.Errorf("invalid ODS file: %w", err)
+	}
+
+	for _, file := range zipReader.File {
+		if file.Name != "content.xml" {
+			continue
+		}
+
+		content, err := file.Open()
+		if err != nil {
+			return QuizData{}, err
+		}
+		defer content.Close()
+
+		rows, err := readODSRows(content)
+		if err != nil {
+			return QuizData{}, fmt.Errorf("invalid ODS content: %w", err)
+		}
+
+		questions, err := parseQuestionRows(rows)
+		if err != nil && !isRowValidationError(err) {
+			return QuizData{}, err
+		}
+		return QuizData{QuizName: quizName, Duration: duration, Category: category, Questions: questions}, err
+	}
+	return QuizData{}, errors.New("invalid ODS file: content.xml not found")
+}
+
+// ✅ Read the rows of the first non-Instructions table in an ODS content.xml
+func readODSRows(r io.Reader) ([][]string, error) {
+	decoder := xml.NewDecoder(r)
+
+	var rows [][]string
+	var row []string
+	var cell strings.Builder
+	var inTable, tableDone bool
+	var annotationDepth, paragraphs, rowRepeat, cellRepeat int
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch {
+			case t.Name.Space == odsTableNS && t.Name.Local == "table":
+				inTable = !tableDone && !strings.EqualFold(odsAttr(t, odsTableNS, "name"), instructionsSheetName)
+			case !inTable:
+			case t.Name.Space == odsOfficeNS && t.Name.Local == "annotation":
+				annotationDepth++
+			case t.Name.Space == odsTableNS && t.Name.Local == "table-row":
+				row = nil
+				rowRepeat = odsRepeat(t, "number-rows-repeated")
+			case t.Name.Space == odsTableNS && (t.Name.Local == "table-cell" || t.Name.Local == "covered-table-cell"):
+				cell.Reset()
+				paragraphs = 0
+				cellRepeat = odsRepeat(t, "number-columns-repeated")
+			case annotationDepth > 0:
+			case t.Name.Space == odsTextNS && t.Name.Local == "p":
+				if paragraphs > 0 {
+					cell.WriteString("\n")
+				}
+				paragraphs++
+			case t.Name.Space == odsTextNS && t.Name.Local == "s":
+				cell.WriteString(strings.Repeat(" ", odsRepeat(t, "c")))
+			case t.Name.Space == odsTextNS && t.Name.Local == "tab":
+				cell.WriteString("\t")
+			case t.Name.Space == odsTextNS && t.Name.Local == "line-break":
+				cell.WriteString("\n")
+			}
+
+		case xml.CharData:
+			if inTable && annotationDepth == 0 {
+				cell.Write(t)
+			}
+
+		case xml.EndElement:
+			switch {
+			case !inTable:
+			case t.Name.Space == odsOfficeNS && t.Name.Local == "annotation":
+				annotationDepth--
+			case t.Name.Space == odsTableNS && (t.Name.Local == "table-cell" || t.Name.Local == "covered-table-cell"):
+				value := cell.String()
+				if value == "" {
+					cellRepeat = min(cellRepeat, odsMaxBlankRepeat)
+				}
+				for i := 0; i < cellRepeat; i++ {
+					row = append(row, value)
+				}
+			case t.Name.Space == odsTableNS && t.Name.Local == "table-row":
+				for len(row) > 0 && row[len(row)-1] == "" {
+					row = row[:len(row)-1]
+				}
+				if len(row) == 0 {
+					rowRepeat = min(rowRepeat, odsMaxBlankRepeat)
+				}
+				for i := 0; i < rowRepeat; i++ {
+					rows = append(rows, row)
+				}
+			case t.Name.Space == odsTableNS && t.Name.Local == "table":
+				inTable = false
+				tableDone = true
+			}
+		}
+	}
+	return rows, nil
+}
+
+// Helper function to read a namespaced attribute from an ODS element
+func odsAttr(element xml.StartElement, space, local string) string {
+	for _, attr := range element.Attr {
+		if attr.Name.Space == space && attr.Name.Local == local {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// Helper function to read a repeat count attribute (defaults to 1)
+func odsRepeat(element xml.StartElement, local string) int {
+	space := odsTableNS
+	if local == "c" {
+		space = odsTextNS
+	}
+	count, err := strconv.Atoi(odsAttr(element, space, local))
+	if err != nil || count < 1 {
+		return 1
+	}
+	return count
+}
+
+// ✅ Process every sheet as its own quiz, named after the sheet
+func processExcelAllSheets(fileBytes []byte, category string, duration int, password string) ([]QuizData, error) {
+	f, err := excelize.OpenReader(bytes.NewReader(fileBytes), excelize.Options{Password: password})
+	if err != nil {
+		return nil, err
+	}
+
+	instructions, err := readInstructionsSheet(f)
+	if err != nil {
+		return nil, err
+	}
+
+	var quizzes []QuizData
+	var rowErrors []RowError
+	for _, sheetName := range f.GetSheetList() {
+		if strings.EqualFold(sheetName, instructionsSheetName) {
+			continue
+		}
+
+		questions, err := parseSheet(f, sheetName)
+		var validationErr *UploadValidationError
+		if errors.As(err, &validationErr) {
+			// Keep going so the report covers every sheet at once
+			for _, rowErr := range validationErr.Rows {
+				rowErr.Sheet = sheetName
+				rowErrors = append(rowErrors, rowErr)
+			}
+		} else if err != nil {
+			return nil, fmt.Errorf("sheet %s: %w", sheetName, err)
+		}
+		if len(questions) == 0 {
+			continue
+		}
+
+		quizzes = append(quizzes, QuizData{QuizName: strings.TrimSpace(sheetName), Duration: duration, Category: category, Instructions: instructions, Questions: questions})
+	}
+
+	if len(rowErrors) > 0 {
+		return quizzes, &UploadValidationError{Rows: rowErrors}
+	}
+	if len(quizzes) == 0 {
+		return nil, errors.New("workbook contains no sheets")
+	}
+	return quizzes, nil
+}
+
+// questionParser converts a header row and then data rows into questions one row
+// at a time, so callers can stream rows instead of holding the whole sheet
+type questionParser struct {
+	headerMap map[string]int
+	rowNumber int
+	questions []Question
+	rowErrors []RowError
+
+	// optionLayout is set when options come from OptionA..OptionE + CorrectOption
+	optionLayout bool
+
+	// translationColumns maps language code -> field column -> index
+	translationColumns map[string]map[string]int
+
+	// richText returns a cell's rich text runs, if the source has any
+	richText func(colIndex, rowNumber int) []excelize.RichTextRun
+
+	// hyperlink returns the URL linked from a cell, if the source has any
+	hyperlink func(colIndex, rowNumber int) string
+
+	// comment returns the note attached to a cell, if the source has any
+	comment func(colIndex, rowNumber int) string
+
+	// rowLimitHit is set once MaxUploadRows is exceeded; streaming callers stop reading
+	rowLimitHit bool
+
+	// mergedQuestionRows maps the first row of a merged Question cell to its last row
+	mergedQuestionRows map[int]int
+
+	// State of the passage question being read: its last row, text, index in
+	// questions (-1 until a valid part is added) and parts read so far
+	groupEnd     int
+	groupPassage string
+	groupIndex   int
+	partRows     int
+}
+
+// ✅ Build a parser from the header row, checking required columns
+func newQuestionParser(header []string) (*questionParser, error) {
+	// Read headers from the first row; exact names win over aliases
+	headerMap := make(map[string]int)
+	for i, name := range header {
+		headerMap[strings.TrimSpace(name)] = i
+	}
+	for i, name := range header {
+		column := canonicalHeader(name)
+		if _, exists := headerMap[column]; !exists {
+			headerMap[column] = i
+		}
+	}
+	// Then spelling variants such as "correct answer" or "Correct_Answer"
+	for i, name := range header {
+		for _, column := range knownColumns {
+			if _, exists := headerMap[column]; !exists && normalizeHeader(name) == normalizeHeader(column) {
+				headerMap[column] = i
+			}
+		}
+	}
+
+	// ✅ Alternative layout: OptionA..OptionE plus a CorrectOption letter
+	_, hasOptionA := headerMap["OptionA"]
+	_, hasCorrectOption := headerMap["CorrectOption"]
+	optionLayout := hasOptionA && hasCorrectOption
+
+	// Required headers
+	var rowErrors []RowError
+	requiredHeaders := []string{"Question", "CorrectAnswer", "IncorrectAnswers", "Explanation"}
+	if optionLayout {
+		requiredHeaders = []string{"Question", "OptionA", "OptionB", "CorrectOption", "Explanation"}
+	}
+	for _, name := range requiredHeaders {
+		if _, exists := headerMap[name]; !exists {
+			reason := "missing required column"
+			if candidate := closestHeader(header, headerMap, name); candidate != "" {
+				reason = fmt.Sprintf("missing required column, did you mean %q?", candidate)
+			}
+			rowErrors = append(rowErrors, RowError{Row: 1, Column: name, Reason: reason})
+		}
+	}
+	if len(rowErrors) > 0 {
+		return nil, &UploadValidationError{Rows: rowErrors}
+	}
+
+	// ✅ Translation columns: <Field>_<LANG>, e.g. Question_TE, Explanation_HI
+	translationColumns := make(map[string]map[string]int)
+	for i, name := range header {
+		field, lang, found := strings.Cut(strings.TrimSpace(name), "_")
+		if !found || len(lang) < 2 || len(lang) > 3 {
+			continue
+		}
+		switch field {
+		case "Question", "CorrectAnswer", "IncorrectAnswers", "Explanation":
+			lang = strings.ToLower(lang)
+			if translationColumns[lang] == nil {
+				translationColumns[lang] = make(map[string]int)
+			}
+			translationColumns[lang][field] = i
+		}
+	}
+
+	// Spreadsheet row numbers are 1-based and the header takes row 1
+	return &questionParser{headerMap: headerMap, rowNumber: 1, translationColumns: translationColumns, optionLayout: optionLayout}, nil
+}
+
+// ✅ Parse and validate the next data row
+func (p *questionParser) addRow(row []string) {
+	p.rowNumber++
+	if isBlankRow(row) || p.rowLimitHit {
+		return
+	}
+	if len(p.questions)+p.partRows >= MaxUploadRows {
+		p.rowLimitHit = true
+		return
+	}
+
+	question := Question{
+		Question:         getCellValue(row, p.headerMap, "Question"),
+		CorrectAnswer:    getCellValue(row, p.headerMap, "CorrectAnswer"),
+		IncorrectAnswers: splitAnswers(getCellValue(row, p.headerMap, "IncorrectAnswers")),
+		Explanation:      getCellValue(row, p.headerMap, "Explanation"),
+		Type:             normalizeQuestionType(getCellValue(row, p.headerMap, "Type")),
+	}
+
+	// ✅ Rows under a merged Question cell are parts of one passage question;
+	// only the first row carries the merged text
+	if groupEnd, startsGroup := p.mergedQuestionRows[p.rowNumber]; startsGroup {
+		p.groupEnd = groupEnd
+		p.groupPassage = question.Question
+		p.groupIndex = -1
+	}
+	inGroup := p.rowNumber <= p.groupEnd
+	if inGroup {
+		question.Question = p.groupPassage
+	}
+
+	var rowErrors []RowError
+	if p.optionLayout {
+		if rowErr := applyOptionColumns(&question, row, p.headerMap); rowErr != nil {
+			rowErrors = append(rowErrors, *rowErr)
+		}
+	}
+	if marks := strings.TrimSpace(getCellValue(row, p.headerMap, "Marks")); marks != "" {
+		value, err := strconv.ParseFloat(marks, 64)
+		if err != nil || value <= 0 {
+			rowErrors = append(rowErrors, RowError{Column: "Marks", Reason: "Marks must be a positive number"})
+		} else {
+			question.Marks = &value
+		}
+	}
+	if difficulty := strings.ToLower(strings.TrimSpace(getCellValue(row, p.headerMap, "Difficulty"))); difficulty != "" {
+		if !containsString(validDifficulties, difficulty) {
+			rowErrors = append(rowErrors, RowError{Column: "Difficulty", Reason: "Difficulty must be easy, medium or hard"})
+		} else {
+			question.Difficulty = difficulty
+		}
+	}
+	if timeSeconds := strings.TrimSpace(getCellValue(row, p.headerMap, "TimeSeconds")); timeSeconds != "" {
+		value, err := strconv.Atoi(timeSeconds)
+		if err != nil || value <= 0 {
+			rowErrors = append(rowErrors, RowError{Column: "TimeSeconds", Reason: "TimeSeconds must be a positive whole number"})
+		} else {
+			question.TimeSeconds = &value
+		}
+	}
+
+	// ✅ Collect non-empty translations per language
+	for lang, columns := range p.translationColumns {
+		translation := QuestionTranslation{
+			Question:      getCellValue(row, columns, "Question"),
+			CorrectAnswer: getCellValue(row, columns, "CorrectAnswer"),
+			Explanation:   getCellValue(row, columns, "Explanation"),
+		}
+		if incorrectAnswers := splitAnswers(getCellValue(row, columns, "IncorrectAnswers")); len(incorrectAnswers) > 0 {
+			translation.IncorrectAnswers = incorrectAnswers
+		}
+		if translation.Question == "" && translation.CorrectAnswer == "" && translation.Explanation == "" && translation.IncorrectAnswers == nil {
+			continue
+		}
+		if question.Translations == nil {
+			question.Translations = make(map[string]QuestionTranslation)
+		}
+		question.Translations[lang] = translation
+	}
+
+	// ✅ LaTeX from the Latex column, or the question itself when it has inline $...$ math
+	latexColumn := "Latex"
+	question.Latex = strings.TrimSpace(getCellValue(row, p.headerMap, "Latex"))
+	if question.Latex == "" && strings.Count(strings.ReplaceAll(question.Question, `\$`, ""), "$") >= 2 {
+		latexColumn = "Question"
+		question.Latex = question.Question
+	}
+	if question.Latex != "" {
+		if err := validateLatex(question.Latex); err != nil {
+			rowErrors = append(rowErrors, RowError{Column: latexColumn, Reason: fmt.Sprintf("invalid LaTeX: %v", err)})
+		}
+	}
+
+	// ✅ Reference link from the ReferenceURL column, else a link on the Question
+	// or Explanation cell
+	question.ReferenceURL = strings.TrimSpace(getCellValue(row, p.headerMap, "ReferenceURL"))
+	if question.ReferenceURL != "" {
+		if !isWebURL(question.ReferenceURL) {
+			rowErrors = append(rowErrors, RowError{Column: "ReferenceURL", Reason: "ReferenceURL must be an http(s) URL"})
+		}
+	} else if p.hyperlink != nil {
+		for _, column := range []string{"Question", "Explanation"} {
+			if index, exists := p.headerMap[column]; exists {
+				// Links to other cells in the workbook are not references
+				if link := p.hyperlink(index, p.rowNumber); isWebURL(link) {
+					question.ReferenceURL = link
+					break
+				}
+			}
+		}
+	}
+
+	// ✅ A note on the Question cell becomes the question's hint
+	if index, exists := p.headerMap["Question"]; exists && p.comment != nil {
+		question.Hint = p.comment(index, p.rowNumber)
+	}
+
+	// Invalid rows are reported but never become questions
+	rowErrors = append(rowErrors, validateQuestion(question)...)
+	if len(rowErrors) > 0 {
+		for _, rowErr := range rowErrors {
+			rowErr.Row = p.rowNumber
+			p.rowErrors = append(p.rowErrors, rowErr)
+		}
+		return
+	}
+	if inGroup {
+		p.addPart(question, strings.TrimSpace(getCellValue(row, p.headerMap, "SubQuestion")))
+		return
+	}
+	if p.richText != nil {
+		p.applyRichText(&question)
+	}
+	question.row = p.rowNumber
+	p.questions = append(p.questions, question)
+}
+
+// ✅ Add a validated row to the current passage question, creating it from the
+// first valid row. Passage questions are kept as plain text
+func (p *questionParser) addPart(source Question, subQuestion string) {
+	if p.groupIndex < 0 {
+		p.questions = append(p.questions, Question{
+			Question:         source.Question,
+			IncorrectAnswers: AnswerList{},
+			Type:             QuestionTypePassage,
+			Latex:            source.Latex,
+			ReferenceURL:     source.ReferenceURL,
+			Hint:             source.Hint,
+			Difficulty:       source.Difficulty,
+			row:              p.rowNumber,
+		})
+		p.groupIndex = len(p.questions) - 1
+	}
+
+	passage := &p.questions[p.groupIndex]
+	passage.Parts = append(passage.Parts, QuestionPart{
+		Question:         subQuestion,
+		CorrectAnswer:    source.CorrectAnswer,
+		IncorrectAnswers: source.IncorrectAnswers,
+		Explanation:      source.Explanation,
+		Type:             source.Type,
+		Marks:            source.Marks,
+	})
+	p.partRows++
+}
+
+// ✅ Replace plain cell text with HTML when any text cell of the row is formatted
+func (p *questionParser) applyRichText(question *Question) {
+	fields := map[string]*string{
+		"Question":      &question.Question,
+		"CorrectAnswer": &question.CorrectAnswer,
+		"Explanation":   &question.Explanation,
+	}
+
+	formatted := make(map[string]string)
+	for column := range fields {
+		if index, exists := p.headerMap[column]; exists {
+			if runs := p.richText(index, p.rowNumber); hasRichFormatting(runs) {
+				formatted[column] = richTextToHTML(runs)
+			}
+		}
+	}
+
+	var incorrectAnswersHTML AnswerList
+	if index, exists := p.headerMap["IncorrectAnswers"]; exists && !p.optionLayout {
+		if runs := p.richText(index, p.rowNumber); hasRichFormatting(runs) {
+			incorrectAnswersHTML = splitRichTextAnswers(runs)
+		}
+	}
+	if len(formatted) == 0 && incorrectAnswersHTML == nil {
+		return
+	}
+
+	// The whole question switches to HTML so clients render every field the same way
+	for column, field := range fields {
+		if value, ok := formatted[column]; ok {
+			*field = value
+		} else {
+			*field = html.EscapeString(*field)
+		}
+	}
+	if incorrectAnswersHTML != nil {
+		question.IncorrectAnswers = incorrectAnswersHTML
+	} else {
+		for i, answer := range question.IncorrectAnswers {
+			question.IncorrectAnswers[i] = html.EscapeString(answer)
+		}
+	}
+	question.Format = QuestionFormatHTML
+}
+
+// ✅ Return the parsed questions and every row problem found; the questions from
+// valid rows are returned alongside an UploadValidationError
+func (p *questionParser) finish() ([]Question, error) {
+	if p.rowLimitHit {
+		return nil, &UploadLimitError{Message: fmt.Sprintf("File exceeds the %d question row limit", MaxUploadRows)}
+	}
+	if len(p.rowErrors) > 0 {
+		return p.questions, &UploadValidationError{Rows: p.rowErrors}
+	}
+	if len(p.questions) == 0 {
+		return nil, errors.New("insufficient data in the file")
+	}
+	return p.questions, nil
+}
+
+// ✅ Convert in-memory rows (header row first) into questions
+func parseQuestionRows(rows [][]string) ([]Question, error) {
+	if len(rows) < 2 {
+		return nil, errors.New("insufficient data in the file")
+	}
+
+	parser, err := newQuestionParser(rows[0])
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows[1:] {
+		parser.addRow(row)
+	}
+	return parser.finish()
+}
+
+// ✅ Stream a worksheet through the parser with excelize's row iterator, so
+// large workbooks are never materialized as a [][]string
+func parseSheet(f *excelize.File, sheetName string) ([]Question, error) {
+	// Rich text, hyperlink and merge lookups load the whole worksheet, so they
+	// are only used on sheets small enough for that to be cheap
+	richFeatures, err := sheetRowsAtMost(f, sheetName, MaxRichSheetRows)
+	if err != nil {
+		return nil, err
+	}
+	if !richFeatures {
+		log.Printf("⚠️ Sheet %s has more than %d rows; ignoring rich text, hyperlinks and merged passages", sheetName, MaxRichSheetRows)
 	}
 
-	return QuizData{QuizName: quizName, Duration: duration, Category: category, Questions: questions}, nil
+	rows, err := f.Rows(sheetName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// Comments live in their own part and never load the worksheet
+	comments, err := getSheetComments(f, sheetName)
+	if err != nil {
+		return nil, err
+	}
+
+	var parser *questionParser
+	for rows.Next() {
+		row, err := rows.Columns()
+		if err != nil {
+			return nil, err
+		}
+
+		if parser == nil {
+			parser, err = newQuestionParser(row)
+			if err != nil {
+				return nil, err
+			}
+			if richFeatures {
+				parser.richText = func(colIndex, rowNumber int) []excelize.RichTextRun {
+					return getCellRichText(f, sheetName, colIndex, rowNumber)
+				}
+				parser.hyperlink = func(colIndex, rowNumber int) string {
+					return getCellLink(f, sheetName, colIndex, rowNumber)
+				}
+				parser.mergedQuestionRows, err = getMergedRowSpans(f, sheetName, parser.headerMap["Question"])
+				if err != nil {
+					return nil, err
+				}
+			}
+			parser.comment = func(colIndex, rowNumber int) string {
+				cell, err := excelize.CoordinatesToCellName(colIndex+1, rowNumber)
+				if err != nil {
+					return ""
+				}
+				return comments[cell]
+			}
+			continue
+		}
+		parser.addRow(row)
+		if parser.rowLimitHit {
+			break
+		}
+	}
+	if err := rows.Error(); err != nil {
+		return nil, err
+	}
+
+	if parser == nil {
+		return nil, errors.New("insufficient data in the file")
+	}
+	return parser.finish()
+}
+
+// ✅ Check whether a sheet has at most limit rows, streaming no further than
+// limit+1 rows
+func sheetRowsAtMost(f *excelize.File, sheetName string, limit int) (bool, error) {
+	rows, err := f.Rows(sheetName)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	count := 0
+	for rows.Next() {
+		if count++; count > limit {
+			return false, nil
+		}
+	}
+	return true, rows.Error()
+}
+
+// ✅ Read a cell's rich text runs (nil for plain cells)
+func getCellRichText(f *excelize.File, sheetName string, colIndex, rowNumber int) []excelize.RichTextRun {
+	cell, err := excelize.CoordinatesToCellName(colIndex+1, rowNumber)
+	if err != nil {
+		return nil
+	}
+	runs, err := f.GetCellRichText(sheetName, cell)
+	if err != nil {
+		return nil
+	}
+	return runs
+}
+
+// ✅ Read the target of a cell's hyperlink ("" when the cell has none)
+func getCellLink(f *excelize.File, sheetName string, colIndex, rowNumber int) string {
+	cell, err := excelize.CoordinatesToCellName(colIndex+1, rowNumber)
+	if err != nil {
+		return ""
+	}
+	hasLink, target, err := f.GetCellHyperLink(sheetName, cell)
+	if err != nil || !hasLink {
+		return ""
+	}
+	return strings.TrimSpace(target)
+}
+
+// ✅ Find cells merged down a column: first row -> last row
+func getMergedRowSpans(f *excelize.File, sheetName string, colIndex int) (map[int]int, error) {
+	mergeCells, err := f.GetMergeCells(sheetName)
+	if err != nil {
+		return nil, err
+	}
+
+	spans := make(map[int]int)
+	for _, mergeCell := range mergeCells {
+		startCol, startRow, err := excelize.CellNameToCoordinates(mergeCell.GetStartAxis())
+		if err != nil {
+			continue
+		}
+		_, endRow, err := excelize.CellNameToCoordinates(mergeCell.GetEndAxis())
+		if err != nil {
+			continue
+		}
+		if startCol == colIndex+1 && endRow > startRow {
+			spans[startRow] = endRow
+		}
+	}
+	return spans, nil
+}
+
+// ✅ Read a sheet's cell comments (notes) as plain text keyed by cell
+func getSheetComments(f *excelize.File, sheetName string) (map[string]string, error) {
+	sheetComments, err := f.GetComments(sheetName)
+	if err != nil {
+		return nil, err
+	}
+
+	comments := make(map[string]string)
+	for _, comment := range sheetComments {
+		text := comment.Text
+		if text == "" {
+			for _, run := range comment.Paragraph {
+				text += run.Text
+			}
+		}
+		// Excel prefixes notes with a bold "Author:" line
+		if comment.Author != "" {
+			text = strings.TrimPrefix(text, comment.Author+":")
+		}
+		if text = strings.TrimSpace(text); text != "" {
+			comments[comment.Cell] = text
+		}
+	}
+	return comments, nil
+}
+
+// Helper function to check for an absolute http(s) URL
+func isWebURL(value string) bool {
+	parsed, err := url.Parse(value)
+	return err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
+}
+
+// Helper function to check whether any run carries formatting we keep
+func hasRichFormatting(runs []excelize.RichTextRun) bool {
+	for _, run := range runs {
+		if run.Text != "" && richTextToHTML([]excelize.RichTextRun{run}) != html.EscapeString(run.Text) {
+			return true
+		}
+	}
+	return false
+}
+
+// ✅ Convert rich text runs to sanitized HTML; only <b>, <i>, <u>, <sup> and
+// <sub> are emitted and all text is escaped
+func richTextToHTML(runs []excelize.RichTextRun) string {
+	var builder strings.Builder
+	for _, run := range runs {
+		text := html.EscapeString(run.Text)
+		if run.Font != nil && text != "" {
+			if run.Font.VertAlign == "superscript" {
+				text = "<sup>" + text + "</sup>"
+			} else if run.Font.VertAlign == "subscript" {
+				text = "<sub>" + text + "</sub>"
+			}
+			if run.Font.Underline != "" && run.Font.Underline != "none" {
+				text = "<u>" + text + "</u>"
+			}
+			if run.Font.Italic {
+				text = "<i>" + text + "</i>"
+			}
+			if run.Font.Bold {
+				text = "<b>" + text + "</b>"
+			}
+		}
+		builder.WriteString(text)
+	}
+	return builder.String()
+}
+
+// ✅ Split a formatted IncorrectAnswers cell on the delimiter into per-answer
+// HTML, applying the same trimming and de-duplication as splitAnswers
+func splitRichTextAnswers(runs []excelize.RichTextRun) AnswerList {
+	var groups [][]excelize.RichTextRun
+	current := []excelize.RichTextRun{}
+	for _, run := range runs {
+		parts := strings.Split(run.Text, incorrectAnswersDelimiter)
+		for i, part := range parts {
+			if i > 0 {
+				groups = append(groups, current)
+				current = []excelize.RichTextRun{}
+			}
+			current = append(current, excelize.RichTextRun{Font: run.Font, Text: part})
+		}
+	}
+	groups = append(groups, current)
+
+	answers := AnswerList{}
+	var seen []string
+	for _, group := range groups {
+		// Trim surrounding whitespace across run boundaries
+		for len(group) > 0 && strings.TrimSpace(group[0].Text) == "" {
+			group = group[1:]
+		}
+		for len(group) > 0 && strings.TrimSpace(group[len(group)-1].Text) == "" {
+			group = group[:len(group)-1]
+		}
+		if len(group) == 0 {
+			continue
+		}
+		group[0].Text = strings.TrimLeftFunc(group[0].Text, unicode.IsSpace)
+		group[len(group)-1].Text = strings.TrimRightFunc(group[len(group)-1].Text, unicode.IsSpace)
+
+		var plain strings.Builder
+		for _, run := range group {
+			plain.WriteString(run.Text)
+		}
+		if containsFold(seen, plain.String()) {
+			continue
+		}
+		seen = append(seen, plain.String())
+		answers = append(answers, richTextToHTML(group))
+	}
+	return answers
+}
+
+// ✅ Convert OptionA..OptionE + CorrectOption ("B", or "A, C" for multi-select)
+// into CorrectAnswer / IncorrectAnswers
+func applyOptionColumns(question *Question, row []string, headerMap map[string]int) *RowError {
+	options := make(map[string]string)
+	var letters []string
+	for _, letter := range []string{"A", "B", "C", "D", "E"} {
+		if option := strings.TrimSpace(getCellValue(row, headerMap, "Option"+letter)); option != "" {
+			options[letter] = option
+			letters = append(letters, letter)
+		}
+	}
+
+	correctLetters := make(map[string]bool)
+	var correctAnswers []string
+	for _, letter := range strings.FieldsFunc(strings.ToUpper(getCellValue(row, headerMap, "CorrectOption")), func(r rune) bool {
+		return r == ',' || r == ' ' || r == ';'
+	}) {
+		letter = strings.TrimPrefix(letter, "OPTION")
+		option, exists := options[letter]
+		if !exists {
+			return &RowError{Column: "CorrectOption", Reason: fmt.Sprintf("CorrectOption %q does not match a filled option", letter)}
+		}
+		if !correctLetters[letter] {
+			correctLetters[letter] = true
+			correctAnswers = append(correctAnswers, option)
+		}
+	}
+
+	var incorrectAnswers []string
+	for _, letter := range letters {
+		if !correctLetters[letter] {
+			incorrectAnswers = append(incorrectAnswers, options[letter])
+		}
+	}
+
+	question.CorrectAnswer = strings.Join(correctAnswers, ", ")
+	question.IncorrectAnswers = normalizeAnswers(incorrectAnswers)
+	if len(correctAnswers) > 1 && question.Type == QuestionTypeMCQ {
+		question.Type = QuestionTypeMultiSelect
+	}
+	return nil
+}
+
+// ✅ Check LaTeX for balanced braces, \left/\right pairs and $ delimiters
+func validateLatex(latex string) error {
+	depth := 0
+	dollars := 0
+	for i := 0; i < len(latex); i++ {
+		switch latex[i] {
+		case '\\':
+			// Skip the escaped character (\{, \}, \$)
+			i++
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth < 0 {
+				return errors.New("unexpected '}'")
+			}
+		case '$':
+			dollars++
+		}
+	}
+	if depth != 0 {
+		return errors.New("unbalanced braces")
+	}
+	if dollars%2 != 0 {
+		return errors.New("unclosed $ delimiter")
+	}
+	if strings.Count(latex, `\left`) != strings.Count(latex, `\right`) {
+		return errors.New(`unmatched \left/\right`)
+	}
+	return nil
+}
+
+// ✅ Per-row validation; Row is filled in by the caller
+func validateQuestion(question Question) []RowError {
+	var rowErrors []RowError
+	if strings.TrimSpace(question.Question) == "" {
+		rowErrors = append(rowErrors, RowError{Column: "Question", Reason: "missing question"})
+	}
+	if utf8.RuneCountInString(question.Question) > MaxQuestionLength {
+		rowErrors = append(rowErrors, RowError{Column: "Question", Reason: fmt.Sprintf("question exceeds %d characters", MaxQuestionLength)})
+	}
+	if strings.TrimSpace(question.CorrectAnswer) == "" {
+		rowErrors = append(rowErrors, RowError{Column: "CorrectAnswer", Reason: "empty CorrectAnswer"})
+		return rowErrors
+	}
+
+	// ✅ An incorrect answer must never also be a correct one
+	correctAnswers := []string{strings.TrimSpace(question.CorrectAnswer)}
+	if question.Type == QuestionTypeMultiSelect {
+		correctAnswers = splitAnswers(question.CorrectAnswer)
+	}
+	for _, answer := range question.IncorrectAnswers {
+		if containsFold(correctAnswers, answer) {
+			rowErrors = append(rowErrors, RowError{Column: "IncorrectAnswers", Reason: fmt.Sprintf("incorrect answer %q equals the CorrectAnswer", answer)})
+		}
+	}
+
+	switch question.Type {
+	case QuestionTypeMCQ, QuestionTypeMultiSelect:
+		if len(question.IncorrectAnswers) == 0 {
+			rowErrors = append(rowErrors, RowError{Column: "IncorrectAnswers", Reason: "malformed IncorrectAnswers: no options given"})
+		}
+	case QuestionTypeTrueFalse:
+		correct := strings.ToLower(strings.TrimSpace(question.CorrectAnswer))
+		if correct != "true" && correct != "false" {
+			rowErrors = append(rowErrors, RowError{Column: "CorrectAnswer", Reason: "true/false answer must be True or False"})
+		} else if len(question.IncorrectAnswers) > 1 || (len(question.IncorrectAnswers) == 1 && !containsFold([]string{"true", "false"}, question.IncorrectAnswers[0])) {
+			rowErrors = append(rowErrors, RowError{Column: "IncorrectAnswers", Reason: "true/false questions only allow the opposite of CorrectAnswer"})
+		}
+	case QuestionTypeNumeric:
+		if _, err := strconv.ParseFloat(strings.TrimSpace(question.CorrectAnswer), 64); err != nil {
+			rowErrors = append(rowErrors, RowError{Column: "CorrectAnswer", Reason: "numeric answer is not a number"})
+		}
+	default:
+		rowErrors = append(rowErrors, RowError{Column: "Type", Reason: fmt.Sprintf("unknown question type: %s", question.Type)})
+	}
+	return rowErrors
+}
+
+// ✅ Split a delimited answers cell, trimming and dropping blanks and repeats
+func splitAnswers(value string) AnswerList {
+	return normalizeAnswers(strings.Split(value, incorrectAnswersDelimiter))
+}
+
+// Helper function to trim answers and drop blanks and case-insensitive repeats
+func normalizeAnswers(values []string) AnswerList {
+	answers := AnswerList{}
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value != "" && !containsFold(answers, value) {
+			answers = append(answers, value)
+		}
+	}
+	return answers
+}
+
+// Helper function for case-insensitive slice membership
+func containsFold(values []string, value string) bool {
+	for _, candidate := range values {
+		if strings.EqualFold(strings.TrimSpace(candidate), strings.TrimSpace(value)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Helper function to map the Type column ("MCQ", "Multi-Select", "True/False", "Numeric") to a QuestionType
+func normalizeQuestionType(value string) string {
+	key := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' {
+			return r
+		}
+		return -1
+	}, strings.ToLower(value))
+
+	switch key {
+	case "", "mcq", "single", "singlechoice", "multiplechoice":
+		return QuestionTypeMCQ
+	case "multiselect", "multipleselect", "multiple":
+		return QuestionTypeMultiSelect
+	case "truefalse", "tf", "boolean":
+		return QuestionTypeTrueFalse
+	case "numeric", "number", "integer":
+		return QuestionTypeNumeric
+	default:
+		return strings.TrimSpace(value)
+	}
+}
+
+// ✅ Drop repeated questions (same text after normalization), reporting each repeat
+func dedupeQuestions(questions []Question) ([]Question, []RowError) {
+	firstSeen := make(map[string]int)
+	var unique []Question
+	var duplicates []RowError
+	for _, question := range questions {
+		key := questionKey(question)
+		if firstRow, exists := firstSeen[key]; exists {
+			duplicates = append(duplicates, RowError{
+				Row:    question.row,
+				Column: "Question",
+				Reason: fmt.Sprintf("duplicate of row %d", firstRow),
+			})
+			continue
+		}
+		firstSeen[key] = question.row
+		unique = append(unique, question)
+	}
+	return unique, duplicates
+}
+
+// Helper function to normalize question text for duplicate detection
+func questionKey(question Question) string {
+	return strings.ToLower(strings.Join(strings.Fields(question.Question), " "))
+}
+
+// Helper function to detect empty rows left over in a sheet
+func isBlankRow(row []string) bool {
+	for _, cell := range row {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
 }
 
 // Helper function to get cell value safely
@@ -458,12 +5885,102 @@ func getCellValue(row []string, headerMap map[string]int, key string) string {
 	return row[index]
 }
 
+// Helper function to escape LIKE/ILIKE wildcards so input matches literally
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
+
+// Helper function to check slice membership
+func containsString(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
+
+// ✅ Suggest valid categories close to a mistyped one (case-insensitive edit distance ≤ 2)
+func suggestCategories(category string) []string {
+	suggestions := []string{}
+	for _, candidate := range validCategories {
+		if levenshtein(strings.ToUpper(category), strings.ToUpper(candidate)) <= 2 {
+			suggestions = append(suggestions, candidate)
+		}
+	}
+	return suggestions
+}
+
+// Helper function computing the edit distance between two strings
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)]
+}
+
+// Helper function to read a request header case-insensitively
+func getHeader(request events.LambdaFunctionURLRequest, name string) string {
+	for key, value := range request.Headers {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return ""
+}
+
+// ✅ Utility: Map upload processing errors to a response
+// Helper function to check for per-row validation failures, which callers may skip
+func isRowValidationError(err error) bool {
+	var validationErr *UploadValidationError
+	return errors.As(err, &validationErr)
+}
+
+func uploadErrorResponse(err error) events.LambdaFunctionURLResponse {
+	var validationErr *UploadValidationError
+	if errors.As(err, &validationErr) {
+		return createJSONResponse(422, map[string]interface{}{
+			"error": "Uploaded file contains invalid rows",
+			"rows":  validationErr.Rows,
+		})
+	}
+	var limitErr *UploadLimitError
+	if errors.As(err, &limitErr) {
+		return createErrorResponse(422, limitErr.Message)
+	}
+	if errors.Is(err, excelize.ErrWorkbookPassword) {
+		return createErrorResponse(400, "Incorrect file password")
+	}
+	var sheetErr *SheetNotFoundError
+	if errors.As(err, &sheetErr) {
+		return createJSONResponse(400, map[string]interface{}{
+			"error":  fmt.Sprintf("Sheet not found: %s", sheetErr.Sheet),
+			"sheets": sheetErr.Available,
+		})
+	}
+	return createErrorResponse(500, "Failed to process Excel file")
+}
+
 // ✅ Utility: Create Success Response
 func createSuccessResponse(message string) events.LambdaFunctionURLResponse {
 	return events.LambdaFunctionURLResponse{
 		StatusCode: 200,
 		Headers:    getCORSHeaders(),
-		Body:       fmt.Sprintf(`{"message":"%s"}`, message),
+		Body:       jsonString(map[string]string{"message": message}),
 	}
 }
 
@@ -472,32 +5989,241 @@ func createErrorResponse(statusCode int, errorMessage string) events.LambdaFunct
 	return events.LambdaFunctionURLResponse{
 		StatusCode: statusCode,
 		Headers:    getCORSHeaders(),
-		Body:       fmt.Sprintf(`{"error":"%s"}`, errorMessage),
+		Body:       jsonString(map[string]string{"error": errorMessage}),
+	}
+}
+
+// Helper function to encode a string map as JSON; messages may quote user input
+func jsonString(payload map[string]string) string {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return `{"error":"Internal server error"}`
+	}
+	return string(body)
+}
+
+// ✅ Utility: Create JSON Response
+func createJSONResponse(statusCode int, payload interface{}) events.LambdaFunctionURLResponse {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("❌ Failed to encode response: %v", err)
+		return createErrorResponse(500, "Internal server error")
+	}
+	return events.LambdaFunctionURLResponse{
+		StatusCode: statusCode,
+		Headers:    getCORSHeaders(),
+		Body:       string(body),
 	}
 }
 
-// ✅ Save Data to PostgreSQL
-func saveToPostgres(quiz QuizData) error {
+// ✅ Upload Modes
+const (
+	UploadModeReplace = "replace"
+	UploadModeAppend  = "append"
+	UploadModeBank    = "bank"
+)
+
+// ✅ Difficulty Levels
+var validDifficulties = []string{"easy", "medium", "hard"}
+
+// ✅ Save Data to PostgreSQL (all quizzes in one transaction), recording a new
+// version of each. New quizzes and ones restored from a soft delete are drafts
+func saveToPostgres(mode string, uploaderEmail string, quizzes ...QuizData) error {
 	db, err := connectDB()
 	if err != nil {
 		return err
 	}
 	defer db.Close()
 
-	questionsJSON, err := json.Marshal(quiz.Questions)
+	tx, err := db.Begin()
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
 	query := `
-		INSERT INTO quiz_questions (quiz_name, duration, category, questions)
-		VALUES ($1, $2, $3, $4::jsonb)
+		INSERT INTO quiz_questions (quiz_name, duration, category, questions, instructions, file_hash, publish_at, close_at, metadata,
+			correct_marks, wrong_penalty, unanswered_marks)
+		VALUES ($1, $2, $3, $4::jsonb, NULLIF($5, ''), NULLIF($6, ''), $7, $8, $9::jsonb, $10, $11, $12)
 		ON CONFLICT (quiz_name)
-		DO UPDATE SET duration = EXCLUDED.duration, category = EXCLUDED.category, questions = EXCLUDED.questions;
+		DO UPDATE SET duration = EXCLUDED.duration, category = EXCLUDED.category, questions = EXCLUDED.questions,
+			instructions = COALESCE(EXCLUDED.instructions, quiz_questions.instructions), file_hash = EXCLUDED.file_hash,
+			publish_at = COALESCE(EXCLUDED.publish_at, quiz_questions.publish_at),
+			close_at = COALESCE(EXCLUDED.close_at, quiz_questions.close_at),
+			metadata = quiz_questions.metadata || EXCLUDED.metadata,
+			correct_marks = COALESCE(EXCLUDED.correct_marks, quiz_questions.correct_marks),
+			wrong_penalty = COALESCE(EXCLUDED.wrong_penalty, quiz_questions.wrong_penalty),
+			unanswered_marks = COALESCE(EXCLUDED.unanswered_marks, quiz_questions.unanswered_marks),
+			status = CASE WHEN quiz_questions.deleted_at IS NULL THEN quiz_questions.status ELSE $13 END,
+			deleted_at = NULL, deleted_by = NULL;
 	`
 
-	_, err = db.Exec(query, quiz.QuizName, quiz.Duration, quiz.Category, questionsJSON)
-	return err
+	for _, quiz := range quizzes {
+		var previousCategory string
+		err := tx.QueryRow("SELECT category FROM quiz_questions WHERE quiz_name = $1 AND deleted_at IS NULL", quiz.QuizName).
+			Scan(&previousCategory)
+		created := errors.Is(err, sql.ErrNoRows)
+		if err != nil && !created {
+			return fmt.Errorf("failed to look up quiz %s: %w", quiz.QuizName, err)
+		}
+
+		if mode == UploadModeAppend {
+			quiz.Questions, err = mergeWithExistingQuestions(tx, quiz)
+			if err != nil {
+				return fmt.Errorf("failed to merge quiz %s: %w", quiz.QuizName, err)
+			}
+		}
+
+		questionsJSON, err := json.Marshal(quiz.Questions)
+		if err != nil {
+			return err
+		}
+		metadataJSON, err := json.Marshal(quiz.Metadata)
+		if err != nil {
+			return err
+		}
+		var correctMarks, wrongPenalty, unansweredMarks interface{}
+		if quiz.Scoring != nil {
+			correctMarks, wrongPenalty, unansweredMarks = quiz.Scoring.CorrectMarks, quiz.Scoring.WrongPenalty, quiz.Scoring.UnansweredMarks
+		}
+
+		_, err = tx.Exec(query, quiz.QuizName, quiz.Duration, quiz.Category, questionsJSON, quiz.Instructions, quiz.FileHash,
+			quiz.PublishAt, quiz.CloseAt, metadataJSON, correctMarks, wrongPenalty, unansweredMarks, QuizStatusDraft)
+		if err != nil {
+			return fmt.Errorf("failed to save quiz %s: %w", quiz.QuizName, err)
+		}
+		if _, err := recordQuizVersion(tx, quiz.QuizName, uploaderEmail); err != nil {
+			return fmt.Errorf("failed to version quiz %s: %w", quiz.QuizName, err)
+		}
+
+		changeType := ContentChangeUpdated
+		if created {
+			changeType = ContentChangeCreated
+		}
+		if err := recordContentChange(tx, changeType, quiz.QuizName, "", uploaderEmail); err != nil {
+			return fmt.Errorf("failed to record change of quiz %s: %w", quiz.QuizName, err)
+		}
+		if !created && previousCategory != quiz.Category {
+			if err := recordContentChange(tx, ContentChangeCategoryChanged, quiz.QuizName, previousCategory, uploaderEmail); err != nil {
+				return fmt.Errorf("failed to record change of quiz %s: %w", quiz.QuizName, err)
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ✅ Save uploaded questions to the question bank (one transaction). Questions
+// are keyed by category and normalized text, so re-uploads update the stored copy
+// and only overwrite topic/difficulty when new values are given. A question's own
+// Difficulty column wins over the upload's difficulty
+func saveToQuestionBank(uploaderEmail, category, topic, difficulty string, quizzes []QuizData) (added, updated int, err error) {
+	db, err := connectDB()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	for _, quiz := range quizzes {
+		for _, question := range quiz.Questions {
+			questionJSON, err := json.Marshal(question)
+			if err != nil {
+				return 0, 0, err
+			}
+			questionDifficulty := difficulty
+			if question.Difficulty != "" {
+				questionDifficulty = question.Difficulty
+			}
+			var inserted bool
+			err = tx.QueryRow(`
+				INSERT INTO question_bank (question_hash, category, topic, difficulty, question, uploaded_by)
+				VALUES ($1, $2, $3, $4, $5::jsonb, $6)
+				ON CONFLICT (question_hash)
+				DO UPDATE SET question = EXCLUDED.question,
+					topic = COALESCE(NULLIF(EXCLUDED.topic, ''), question_bank.topic),
+					difficulty = COALESCE(NULLIF(EXCLUDED.difficulty, ''), question_bank.difficulty),
+					updated_at = NOW()
+				RETURNING xmax = 0`,
+				bankQuestionHash(category, question), category, topic, questionDifficulty, questionJSON, uploaderEmail).Scan(&inserted)
+			if err != nil {
+				return 0, 0, fmt.Errorf("failed to save question %q: %w", question.Question, err)
+			}
+			if inserted {
+				added++
+			} else {
+				updated++
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, err
+	}
+	log.Printf("🏦 %s added %d and updated %d question(s) in the %s question bank", uploaderEmail, added, updated, category)
+	return added, updated, nil
+}
+
+// Helper function to identify a question bank entry
+func bankQuestionHash(category string, question Question) string {
+	digest := sha256.Sum256([]byte(strings.ToLower(category) + "\n" + questionKey(question)))
+	return hex.EncodeToString(digest[:])
+}
+
+// ✅ Snapshot the stored quiz as its next version. The quiz row is already
+// locked by the caller's write, so version numbers can't race
+func recordQuizVersion(tx *sql.Tx, quizName, uploadedBy string) (int, error) {
+	var version int
+	err := tx.QueryRow(`
+		INSERT INTO quiz_versions (quiz_name, version, duration, category, questions, instructions, uploaded_by,
+			metadata, correct_marks, wrong_penalty, unanswered_marks, publish_at, close_at)
+		SELECT quiz_name,
+			COALESCE((SELECT MAX(version) FROM quiz_versions WHERE quiz_name = $1), 0) + 1,
+			duration, category, questions, instructions, $2,
+			metadata, correct_marks, wrong_penalty, unanswered_marks, publish_at, close_at
+		FROM quiz_questions WHERE quiz_name = $1
+		RETURNING version`, quizName, uploadedBy).Scan(&version)
+	return version, err
+}
+
+// ✅ Append new questions to the stored quiz, skipping ones it already has
+func mergeWithExistingQuestions(tx *sql.Tx, quiz QuizData) ([]Question, error) {
+	var existingJSON []byte
+	err := tx.QueryRow("SELECT questions FROM quiz_questions WHERE quiz_name = $1 AND deleted_at IS NULL FOR UPDATE", quiz.QuizName).Scan(&existingJSON)
+	if errors.Is(err, sql.ErrNoRows) {
+		return quiz.Questions, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var existing []Question
+	if err := json.Unmarshal(existingJSON, &existing); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(existing))
+	for _, question := range existing {
+		seen[questionKey(question)] = true
+	}
+
+	merged := existing
+	skipped := 0
+	for _, question := range quiz.Questions {
+		if seen[questionKey(question)] {
+			skipped++
+			continue
+		}
+		merged = append(merged, question)
+	}
+
+	log.Printf("➕ Appending %d question(s) to %s (%d already present)", len(merged)-len(existing), quiz.QuizName, skipped)
+	return merged, nil
 }
 
 // ✅ Main Function
@@ -505,5 +6231,8 @@ func main() {
 	if err := initFirebase(); err != nil {
 		log.Fatalf("Failed to initialize Firebase: %v", err)
 	}
+	if err := initUploadHooks(); err != nil {
+		log.Fatalf("Failed to initialize upload hooks: %v", err)
+	}
 	lambda.Start(lambdaHandler)
 }
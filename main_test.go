@@ -0,0 +1,594 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Helper function to build a workbook with a header row and rowCount questions
+func buildQuestionWorkbook(t *testing.T, rowCount int) *excelize.File {
+	t.Helper()
+	source := excelize.NewFile()
+	defer source.Close()
+
+	writer, err := source.NewStreamWriter("Sheet1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := []interface{}{"Question", "CorrectAnswer", "IncorrectAnswers", "Explanation"}
+	if err := writer.SetRow("A1", header); err != nil {
+		t.Fatal(err)
+	}
+	incorrect := strings.Join([]string{"1", "2", "3"}, incorrectAnswersDelimiter)
+	for i := 0; i < rowCount; i++ {
+		cell, _ := excelize.CoordinatesToCellName(1, i+2)
+		row := []interface{}{fmt.Sprintf("What is %d + 4?", i), "4", incorrect, "Add the numbers"}
+		if err := writer.SetRow(cell, row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	buffer, err := source.WriteToBuffer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := excelize.OpenReader(buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+// excelize caches a worksheet in f.Sheet once anything decodes the whole of it,
+// so an empty cache after parsing means the sheet was only ever streamed
+func TestParseSheetStreamsLargeSheets(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a large workbook")
+	}
+	const sheetPath = "xl/worksheets/sheet1.xml"
+
+	tests := []struct {
+		name        string
+		rowCount    int
+		wantDecoded bool
+	}{
+		{name: "small sheet uses rich features", rowCount: MaxRichSheetRows / 2, wantDecoded: true},
+		{name: "large sheet is only streamed", rowCount: MaxRichSheetRows * 4, wantDecoded: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := buildQuestionWorkbook(t, tt.rowCount)
+
+			questions, err := parseSheet(f, "Sheet1")
+			if err != nil {
+				t.Fatalf("parseSheet: %v", err)
+			}
+			if len(questions) != tt.rowCount {
+				t.Errorf("got %d questions, want %d", len(questions), tt.rowCount)
+			}
+			if _, decoded := f.Sheet.Load(sheetPath); decoded != tt.wantDecoded {
+				t.Errorf("worksheet decoded = %v, want %v", decoded, tt.wantDecoded)
+			}
+		})
+	}
+}
+
+func TestSplitAnswers(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  AnswerList
+	}{
+		{name: "trims each answer", value: " 3 , 5,7 ", want: AnswerList{"3", "5", "7"}},
+		{name: "drops blanks", value: "3,, ,5,", want: AnswerList{"3", "5"}},
+		{name: "drops case-insensitive repeats", value: "Paris, paris ,PARIS", want: AnswerList{"Paris"}},
+		{name: "empty cell", value: "", want: AnswerList{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value := strings.ReplaceAll(tt.value, ",", incorrectAnswersDelimiter)
+			if got := splitAnswers(value); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitAnswers(%q) = %q, want %q", value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateQuestion(t *testing.T) {
+	tests := []struct {
+		name        string
+		question    Question
+		wantColumns []string
+	}{
+		{
+			name:     "valid mcq",
+			question: Question{Question: "2 + 2?", CorrectAnswer: "4", IncorrectAnswers: AnswerList{"3", "5"}, Type: QuestionTypeMCQ},
+		},
+		{
+			name:        "missing question",
+			question:    Question{CorrectAnswer: "4", IncorrectAnswers: AnswerList{"3"}, Type: QuestionTypeMCQ},
+			wantColumns: []string{"Question"},
+		},
+		{
+			name:        "question too long",
+			question:    Question{Question: strings.Repeat("x", MaxQuestionLength+1), CorrectAnswer: "4", IncorrectAnswers: AnswerList{"3"}, Type: QuestionTypeMCQ},
+			wantColumns: []string{"Question"},
+		},
+		{
+			name:        "empty correct answer stops further checks",
+			question:    Question{Question: "2 + 2?", Type: "essay"},
+			wantColumns: []string{"CorrectAnswer"},
+		},
+		{
+			name:        "incorrect answer repeats the correct one",
+			question:    Question{Question: "Capital of France?", CorrectAnswer: "Paris", IncorrectAnswers: AnswerList{"paris", "Rome"}, Type: QuestionTypeMCQ},
+			wantColumns: []string{"IncorrectAnswers"},
+		},
+		{
+			name:        "mcq without options",
+			question:    Question{Question: "2 + 2?", CorrectAnswer: "4", Type: QuestionTypeMCQ},
+			wantColumns: []string{"IncorrectAnswers"},
+		},
+		{
+			name:     "multi-select checks every correct answer",
+			question: Question{Question: "Primes?", CorrectAnswer: "2, 3", IncorrectAnswers: AnswerList{"4", "6"}, Type: QuestionTypeMultiSelect},
+		},
+		{
+			name:        "multi-select option listed as both",
+			question:    Question{Question: "Primes?", CorrectAnswer: "2, 3", IncorrectAnswers: AnswerList{"3", "4"}, Type: QuestionTypeMultiSelect},
+			wantColumns: []string{"IncorrectAnswers"},
+		},
+		{
+			name:     "true/false with opposite option",
+			question: Question{Question: "The sky is blue", CorrectAnswer: "True", IncorrectAnswers: AnswerList{"False"}, Type: QuestionTypeTrueFalse},
+		},
+		{
+			name:        "true/false answer is not a boolean",
+			question:    Question{Question: "The sky is blue", CorrectAnswer: "Yes", Type: QuestionTypeTrueFalse},
+			wantColumns: []string{"CorrectAnswer"},
+		},
+		{
+			name:        "true/false with a non-boolean option",
+			question:    Question{Question: "The sky is blue", CorrectAnswer: "True", IncorrectAnswers: AnswerList{"Maybe"}, Type: QuestionTypeTrueFalse},
+			wantColumns: []string{"IncorrectAnswers"},
+		},
+		{
+			name:     "numeric answer",
+			question: Question{Question: "Pi to two places?", CorrectAnswer: "3.14", Type: QuestionTypeNumeric},
+		},
+		{
+			name:        "numeric answer is not a number",
+			question:    Question{Question: "Pi to two places?", CorrectAnswer: "three", Type: QuestionTypeNumeric},
+			wantColumns: []string{"CorrectAnswer"},
+		},
+		{
+			name:        "unknown type",
+			question:    Question{Question: "Describe Paris", CorrectAnswer: "A city", Type: "essay"},
+			wantColumns: []string{"Type"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotColumns []string
+			for _, rowErr := range validateQuestion(tt.question) {
+				gotColumns = append(gotColumns, rowErr.Column)
+			}
+			if !reflect.DeepEqual(gotColumns, tt.wantColumns) {
+				t.Errorf("error columns = %q, want %q", gotColumns, tt.wantColumns)
+			}
+		})
+	}
+}
+
+func TestValidateLatex(t *testing.T) {
+	tests := []struct {
+		latex   string
+		wantErr string
+	}{
+		{latex: `\frac{a}{b}`},
+		{latex: `$x^2$ and $y^2$`},
+		{latex: `\left( \frac{1}{2} \right)`},
+		{latex: `\{ escaped \} and \$`},
+		{latex: `\frac{a}{b`, wantErr: "unbalanced braces"},
+		{latex: `}{`, wantErr: "unexpected '}'"},
+		{latex: `$x^2`, wantErr: "unclosed $ delimiter"},
+		{latex: `\left( x`, wantErr: `unmatched \left/\right`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.latex, func(t *testing.T) {
+			err := validateLatex(tt.latex)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validateLatex(%q) = %v, want nil", tt.latex, err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("validateLatex(%q) = %v, want %q", tt.latex, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// Helper function to build a zip archive; stored entries keep their bytes
+// readable at the start of the file, as the ODS mimetype entry is
+func buildZip(t *testing.T, entries ...string) []byte {
+	t.Helper()
+	var buffer bytes.Buffer
+	writer := zip.NewWriter(&buffer)
+	for i := 0; i+1 < len(entries); i += 2 {
+		entry, err := writer.CreateHeader(&zip.FileHeader{Name: entries[i], Method: zip.Store})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := entry.Write([]byte(entries[i+1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buffer.Bytes()
+}
+
+func TestDetectFileFormat(t *testing.T) {
+	ole2 := []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+	tests := []struct {
+		name      string
+		fileBytes []byte
+		want      string
+	}{
+		{name: "xlsx", fileBytes: buildZip(t, "[Content_Types].xml", "<Types/>", "xl/workbook.xml", "<workbook/>"), want: fileFormatXLSX},
+		{name: "ods", fileBytes: buildZip(t, "mimetype", "application/vnd.oasis.opendocument.spreadsheet", "content.xml", "<document/>"), want: fileFormatODS},
+		{name: "zip of workbooks", fileBytes: buildZip(t, "week1.xlsx", "PK", "week2.xlsx", "PK"), want: fileFormatZIP},
+		{name: "unreadable zip is treated as xlsx", fileBytes: []byte("PK\x03\x04 truncated"), want: fileFormatXLSX},
+		{name: "legacy xls", fileBytes: append(append([]byte{}, ole2...), "Workbook"...), want: fileFormatXLS},
+		{name: "encrypted xlsx", fileBytes: append(append([]byte{}, ole2...), "E\x00n\x00c\x00r\x00y\x00p\x00t\x00i\x00o\x00n\x00I\x00n\x00f\x00o\x00"...), want: fileFormatEncrypted},
+		{name: "csv", fileBytes: []byte("Question,CorrectAnswer\n2 + 2?,4\n"), want: fileFormatUnknown},
+		{name: "empty", fileBytes: nil, want: fileFormatUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectFileFormat(tt.fileBytes); got != tt.want {
+				t.Errorf("detectFileFormat() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// Helper function to wrap ODS tables in a content.xml document
+func odsContent(tables string) string {
+	return `<office:document-content xmlns:office="` + odsOfficeNS + `" xmlns:table="` + odsTableNS + `" xmlns:text="` + odsTextNS + `">` +
+		`<office:body><office:spreadsheet>` + tables + `</office:spreadsheet></office:body></office:document-content>`
+}
+
+func TestReadODSRows(t *testing.T) {
+	tests := []struct {
+		name   string
+		tables string
+		want   [][]string
+	}{
+		{
+			name: "plain cells",
+			tables: `<table:table table:name="Quiz">` +
+				`<table:table-row><table:table-cell><text:p>Question</text:p></table:table-cell><table:table-cell><text:p>CorrectAnswer</text:p></table:table-cell></table:table-row>` +
+				`<table:table-row><table:table-cell><text:p>2 + 2?</text:p></table:table-cell><table:table-cell><text:p>4</text:p></table:table-cell></table:table-row>` +
+				`</table:table>`,
+			want: [][]string{{"Question", "CorrectAnswer"}, {"2 + 2?", "4"}},
+		},
+		{
+			name: "skips the Instructions table and reads only the first quiz table",
+			tables: `<table:table table:name="Instructions"><table:table-row><table:table-cell><text:p>Read me</text:p></table:table-cell></table:table-row></table:table>` +
+				`<table:table table:name="Quiz"><table:table-row><table:table-cell><text:p>first</text:p></table:table-cell></table:table-row></table:table>` +
+				`<table:table table:name="Other"><table:table-row><table:table-cell><text:p>second</text:p></table:table-cell></table:table-row></table:table>`,
+			want: [][]string{{"first"}},
+		},
+		{
+			name: "expands repeats and trims trailing blank cells",
+			tables: `<table:table table:name="Quiz">` +
+				`<table:table-row table:number-rows-repeated="2"><table:table-cell table:number-columns-repeated="2"><text:p>x</text:p></table:table-cell><table:table-cell table:number-columns-repeated="3"/></table:table-row>` +
+				`</table:table>`,
+			want: [][]string{{"x", "x"}, {"x", "x"}},
+		},
+		{
+			name: "keeps paragraphs, spaces and line breaks but drops comments",
+			tables: `<table:table table:name="Quiz"><table:table-row><table:table-cell>` +
+				`<office:annotation><text:p>reviewer note</text:p></office:annotation>` +
+				`<text:p>a<text:s text:c="2"/>b</text:p><text:p>c<text:line-break/>d</text:p>` +
+				`</table:table-cell></table:table-row></table:table>`,
+			want: [][]string{{"a  b\nc\nd"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readODSRows(strings.NewReader(odsContent(tt.tables)))
+			if err != nil {
+				t.Fatalf("readODSRows: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readODSRows() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadHeaderAliases(t *testing.T) {
+	tests := []struct {
+		name      string
+		env       string
+		alias     string
+		wantAlias string
+	}{
+		{name: "built-in alias", alias: "q", wantAlias: "Question"},
+		{name: "configured alias is lower-cased", env: `{" Prompt ": "Question"}`, alias: "prompt", wantAlias: "Question"},
+		{name: "configured alias keeps the built-ins", env: `{"Prompt": "Question"}`, alias: "answer", wantAlias: "CorrectAnswer"},
+		{name: "invalid JSON falls back to the built-ins", env: `{"Prompt":`, alias: "prompt", wantAlias: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("HEADER_ALIASES", tt.env)
+			if got := loadHeaderAliases()[tt.alias]; got != tt.wantAlias {
+				t.Errorf("alias %q = %q, want %q", tt.alias, got, tt.wantAlias)
+			}
+		})
+	}
+}
+
+func TestNewQuestionParserAliases(t *testing.T) {
+	tests := []struct {
+		name       string
+		header     []string
+		wantColumn map[string]int
+	}{
+		{
+			name:       "exact names",
+			header:     []string{"Question", "CorrectAnswer", "IncorrectAnswers", "Explanation"},
+			wantColumn: map[string]int{"Question": 0, "CorrectAnswer": 1, "IncorrectAnswers": 2, "Explanation": 3},
+		},
+		{
+			name:       "aliases in any case",
+			header:     []string{"Q", "Answer", "IncorrectAnswers", "Explanation", "Link"},
+			wantColumn: map[string]int{"Question": 0, "CorrectAnswer": 1, "ReferenceURL": 4},
+		},
+		{
+			name:       "exact name wins over an alias",
+			header:     []string{"Q", "Question", "CorrectAnswer", "IncorrectAnswers", "Explanation"},
+			wantColumn: map[string]int{"Question": 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser, err := newQuestionParser(tt.header)
+			if err != nil {
+				t.Fatalf("newQuestionParser: %v", err)
+			}
+			for column, index := range tt.wantColumn {
+				if got, exists := parser.headerMap[column]; !exists || got != index {
+					t.Errorf("headerMap[%q] = %d (present %v), want %d", column, got, exists, index)
+				}
+			}
+		})
+	}
+}
+
+func TestNewQuestionParserSpellingVariants(t *testing.T) {
+	tests := []struct {
+		name       string
+		header     []string
+		wantColumn map[string]int
+		wantReason string
+	}{
+		{
+			name:       "case, space, underscore and hyphen variants",
+			header:     []string{"question", "Correct Answer", "incorrect_answers", "EXPLANATION", "time-seconds"},
+			wantColumn: map[string]int{"Question": 0, "CorrectAnswer": 1, "IncorrectAnswers": 2, "Explanation": 3, "TimeSeconds": 4},
+		},
+		{
+			name:       "typo suggests the closest header",
+			header:     []string{"Question", "CorrectAnswer", "IncorrectAnswers", "Explaination"},
+			wantReason: `missing required column, did you mean "Explaination"?`,
+		},
+		{
+			name:       "unrelated header gets no suggestion",
+			header:     []string{"Question", "CorrectAnswer", "IncorrectAnswers", "Notes"},
+			wantReason: "missing required column",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser, err := newQuestionParser(tt.header)
+			if tt.wantReason != "" {
+				var validationErr *UploadValidationError
+				if !errors.As(err, &validationErr) || len(validationErr.Rows) != 1 {
+					t.Fatalf("newQuestionParser() error = %v, want one missing column", err)
+				}
+				if got := validationErr.Rows[0]; got.Column != "Explanation" || got.Reason != tt.wantReason {
+					t.Errorf("row error = %+v, want Explanation: %s", got, tt.wantReason)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("newQuestionParser: %v", err)
+			}
+			for column, index := range tt.wantColumn {
+				if got, exists := parser.headerMap[column]; !exists || got != index {
+					t.Errorf("headerMap[%q] = %d (present %v), want %d", column, got, exists, index)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyOptionColumns(t *testing.T) {
+	headerMap := map[string]int{"OptionA": 0, "OptionB": 1, "OptionC": 2, "OptionD": 3, "CorrectOption": 4}
+	tests := []struct {
+		name          string
+		row           []string
+		wantCorrect   string
+		wantIncorrect AnswerList
+		wantType      string
+		wantErr       bool
+	}{
+		{name: "single letter", row: []string{"3", "4", "5", "", "B"}, wantCorrect: "4", wantIncorrect: AnswerList{"3", "5"}, wantType: QuestionTypeMCQ},
+		{name: "lower case with Option prefix", row: []string{"3", "4", "5", "", "optionc"}, wantCorrect: "5", wantIncorrect: AnswerList{"3", "4"}, wantType: QuestionTypeMCQ},
+		{name: "several letters become multi-select", row: []string{"2", "3", "4", "5", "A, B"}, wantCorrect: "2, 3", wantIncorrect: AnswerList{"4", "5"}, wantType: QuestionTypeMultiSelect},
+		{name: "semicolons and repeats", row: []string{"2", "3", "4", "", "A;A"}, wantCorrect: "2", wantIncorrect: AnswerList{"3", "4"}, wantType: QuestionTypeMCQ},
+		{name: "letter of an empty option", row: []string{"3", "4", "", "", "C"}, wantErr: true},
+		{name: "letter past the last column", row: []string{"3", "4", "5", "6", "E"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			question := Question{Type: QuestionTypeMCQ}
+			rowErr := applyOptionColumns(&question, tt.row, headerMap)
+			if tt.wantErr {
+				if rowErr == nil || rowErr.Column != "CorrectOption" {
+					t.Errorf("applyOptionColumns() error = %v, want a CorrectOption error", rowErr)
+				}
+				return
+			}
+			if rowErr != nil {
+				t.Fatalf("applyOptionColumns: %+v", rowErr)
+			}
+			if question.CorrectAnswer != tt.wantCorrect || !reflect.DeepEqual(question.IncorrectAnswers, tt.wantIncorrect) || question.Type != tt.wantType {
+				t.Errorf("got %q / %q / %s, want %q / %q / %s", question.CorrectAnswer, question.IncorrectAnswers, question.Type,
+					tt.wantCorrect, tt.wantIncorrect, tt.wantType)
+			}
+		})
+	}
+}
+
+func TestBulkDeleteToken(t *testing.T) {
+	previewed := []string{"Algebra 1", "Algebra 2"}
+	tests := []struct {
+		name      string
+		quizNames []string
+		wantMatch bool
+	}{
+		{name: "same set", quizNames: []string{"Algebra 1", "Algebra 2"}, wantMatch: true},
+		{name: "quiz added since the preview", quizNames: []string{"Algebra 1", "Algebra 2", "Algebra 3"}},
+		{name: "quiz removed since the preview", quizNames: []string{"Algebra 1"}},
+		{name: "nothing matched", quizNames: nil},
+	}
+	want := bulkDeleteToken(previewed)
+	if len(want) != 64 {
+		t.Fatalf("token %q is not a hex SHA-256 digest", want)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := bulkDeleteToken(tt.quizNames); (got == want) != tt.wantMatch {
+				t.Errorf("bulkDeleteToken(%q) matches the preview = %v, want %v", tt.quizNames, got == want, tt.wantMatch)
+			}
+		})
+	}
+}
+
+// Helper function to evaluate a SQL LIKE pattern with ESCAPE '\'
+func sqlLike(value, pattern string) bool {
+	var expr strings.Builder
+	expr.WriteString("^")
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); i++ {
+		switch runes[i] {
+		case '\\':
+			if i+1 < len(runes) {
+				i++
+			}
+			expr.WriteString(regexp.QuoteMeta(string(runes[i])))
+		case '%':
+			expr.WriteString("(?s:.*)")
+		case '_':
+			expr.WriteString("(?s:.)")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(runes[i])))
+		}
+	}
+	expr.WriteString("$")
+	return regexp.MustCompile(expr.String()).MatchString(value)
+}
+
+func TestClassCategoryPattern(t *testing.T) {
+	tests := []struct {
+		class    string
+		category string
+		want     bool
+	}{
+		{class: "CLS1", category: "CLS1", want: true},
+		{class: "CLS1", category: "CLS1-MATH", want: true},
+		{class: "CLS1", category: "CLS10-MATH", want: false},
+		{class: "CLS1", category: "CLS12", want: false},
+		{class: "CLS10", category: "CLS10-PHYSICS", want: true},
+		{class: "CLS_1", category: "CLSX1-MATH", want: false},
+		{class: "CLS_1", category: "CLS_1-MATH", want: true},
+		{class: "CLS%", category: "CLS10-MATH", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.class+"/"+tt.category, func(t *testing.T) {
+			// Mirrors handleGetSchedule: category = $1 OR category LIKE $2 ESCAPE '\'
+			got := tt.category == tt.class || sqlLike(tt.category, classCategoryPattern(tt.class))
+			if got != tt.want {
+				t.Errorf("class %q matches category %q = %v, want %v", tt.class, tt.category, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEscapeLike(t *testing.T) {
+	tests := []struct {
+		input string
+		value string
+		want  bool
+	}{
+		{input: "Algebra", value: "Algebra", want: true},
+		{input: "50%", value: "50%", want: true},
+		{input: "50%", value: "500", want: false},
+		{input: "a_b", value: "a_b", want: true},
+		{input: "a_b", value: "axb", want: false},
+		{input: `C:\notes`, value: `C:\notes`, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input+"/"+tt.value, func(t *testing.T) {
+			if got := sqlLike(tt.value, escapeLike(tt.input)); got != tt.want {
+				t.Errorf("%q LIKE escapeLike(%q) = %v, want %v", tt.value, tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInitUploadHooks(t *testing.T) {
+	tests := []struct {
+		name            string
+		env             string
+		wantErr         bool
+		wantPreValidate int
+	}{
+		{name: "no hooks", env: ""},
+		{name: "registered hook", env: "quiz-name-pattern", wantPreValidate: 1},
+		{name: "unknown hook", env: "quiz-name-pattern, no-such-hook", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("UPLOAD_HOOKS", tt.env)
+			t.Cleanup(func() { enabledPreValidateHooks, enabledPostSaveHooks = nil, nil })
+
+			err := initUploadHooks()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("initUploadHooks() error = %v, want error %v", err, tt.wantErr)
+			}
+			if got := len(enabledPreValidateHooks); got != tt.wantPreValidate {
+				t.Errorf("got %d pre-validate hooks, want %d", got, tt.wantPreValidate)
+			}
+		})
+	}
+}
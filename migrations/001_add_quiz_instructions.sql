@@ -0,0 +1,2 @@
+-- Per-quiz instructions shown by GET /quizzes/{name}/info before the quiz starts
+ALTER TABLE quiz_questions ADD COLUMN IF NOT EXISTS instructions TEXT;
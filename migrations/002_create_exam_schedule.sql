@@ -0,0 +1,11 @@
+-- Exam timetable managed via /admin/schedule and read by GET /schedule
+CREATE TABLE IF NOT EXISTS exam_schedule (
+    id          SERIAL PRIMARY KEY,
+    exam_date   DATE NOT NULL,
+    category    TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    created_by  TEXT,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS exam_schedule_exam_date_idx ON exam_schedule (exam_date);
@@ -0,0 +1,25 @@
+-- Student doubts linked to a quiz question or a topic, answered by staff
+CREATE TABLE IF NOT EXISTS doubts (
+    id             SERIAL PRIMARY KEY,
+    student_email  TEXT NOT NULL,
+    quiz_name      TEXT,
+    question_index INTEGER,
+    topic          TEXT,
+    body           TEXT NOT NULL,
+    status         TEXT NOT NULL DEFAULT 'open',
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    resolved_at    TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS doubts_student_email_idx ON doubts (student_email);
+CREATE INDEX IF NOT EXISTS doubts_quiz_name_idx ON doubts (quiz_name);
+
+CREATE TABLE IF NOT EXISTS doubt_replies (
+    id           SERIAL PRIMARY KEY,
+    doubt_id     INTEGER NOT NULL REFERENCES doubts (id) ON DELETE CASCADE,
+    author_email TEXT NOT NULL,
+    body         TEXT NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS doubt_replies_doubt_id_idx ON doubt_replies (doubt_id);
@@ -0,0 +1,16 @@
+-- One row per /upload/questions call, read by GET /upload/history
+CREATE TABLE IF NOT EXISTS upload_audit (
+    id             SERIAL PRIMARY KEY,
+    uploader_email TEXT NOT NULL,
+    quiz_name      TEXT NOT NULL DEFAULT '',
+    category       TEXT NOT NULL DEFAULT '',
+    row_count      INTEGER NOT NULL DEFAULT 0,
+    file_hash      TEXT NOT NULL DEFAULT '',
+    status_code    INTEGER NOT NULL,
+    outcome        TEXT NOT NULL,
+    message        TEXT,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS upload_audit_created_at_idx ON upload_audit (created_at);
+CREATE INDEX IF NOT EXISTS upload_audit_uploader_email_idx ON upload_audit (uploader_email);
@@ -0,0 +1,4 @@
+-- SHA-256 of the uploaded file, used to short-circuit retried uploads
+ALTER TABLE quiz_questions ADD COLUMN IF NOT EXISTS file_hash TEXT;
+
+CREATE INDEX IF NOT EXISTS quiz_questions_file_hash_idx ON quiz_questions (file_hash);
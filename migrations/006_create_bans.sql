@@ -0,0 +1,14 @@
+-- Soft bans for abusive accounts, separate from account deactivation
+CREATE TABLE IF NOT EXISTS bans (
+    id         SERIAL PRIMARY KEY,
+    email      TEXT NOT NULL,
+    scope      TEXT NOT NULL,
+    reason     TEXT NOT NULL,
+    banned_by  TEXT NOT NULL,
+    expires_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    lifted_at  TIMESTAMPTZ,
+    lifted_by  TEXT
+);
+
+CREATE INDEX IF NOT EXISTS bans_email_idx ON bans (email);
@@ -0,0 +1,7 @@
+-- Soft delete for quizzes (bulk cleanup) and creation time for date filters.
+-- Existing quizzes get the migration time as created_at.
+ALTER TABLE quiz_questions ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+ALTER TABLE quiz_questions ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+ALTER TABLE quiz_questions ADD COLUMN IF NOT EXISTS deleted_by TEXT;
+
+CREATE INDEX IF NOT EXISTS quiz_questions_category_idx ON quiz_questions (category);
@@ -0,0 +1,10 @@
+-- Append-only support notes on a student (call outcomes, complaints, payment promises)
+CREATE TABLE IF NOT EXISTS student_notes (
+    id            SERIAL PRIMARY KEY,
+    student_email TEXT NOT NULL,
+    author_email  TEXT NOT NULL,
+    body          TEXT NOT NULL,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS student_notes_student_email_idx ON student_notes (student_email);
@@ -0,0 +1,4 @@
+-- Firebase uid linked on first phone-auth sign-in (phone tokens carry no email)
+ALTER TABLE students ADD COLUMN IF NOT EXISTS firebase_uid TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS students_firebase_uid_idx ON students (firebase_uid);
@@ -0,0 +1,13 @@
+-- Every saved question set of a quiz, newest version last, used for rollback
+CREATE TABLE IF NOT EXISTS quiz_versions (
+    id           SERIAL PRIMARY KEY,
+    quiz_name    TEXT NOT NULL,
+    version      INTEGER NOT NULL,
+    duration     INTEGER NOT NULL,
+    category     TEXT NOT NULL,
+    questions    JSONB NOT NULL,
+    instructions TEXT,
+    uploaded_by  TEXT NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (quiz_name, version)
+);
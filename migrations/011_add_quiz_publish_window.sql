@@ -0,0 +1,3 @@
+-- Optional window in which students can see a quiz (NULL = no bound)
+ALTER TABLE quiz_questions ADD COLUMN IF NOT EXISTS publish_at TIMESTAMPTZ;
+ALTER TABLE quiz_questions ADD COLUMN IF NOT EXISTS close_at TIMESTAMPTZ;
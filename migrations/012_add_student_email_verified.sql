@@ -0,0 +1,3 @@
+-- Firebase email_verified status, copied by POST /admin/students/sync-verification
+ALTER TABLE students ADD COLUMN IF NOT EXISTS email_verified BOOLEAN;
+ALTER TABLE students ADD COLUMN IF NOT EXISTS email_verified_synced_at TIMESTAMPTZ;
@@ -0,0 +1,4 @@
+-- Finer-grained quiz metadata (topic, chapter, examYear, author, tags)
+ALTER TABLE quiz_questions ADD COLUMN IF NOT EXISTS metadata JSONB NOT NULL DEFAULT '{}';
+
+CREATE INDEX IF NOT EXISTS quiz_questions_metadata_idx ON quiz_questions USING GIN (metadata);
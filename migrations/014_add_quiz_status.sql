@@ -0,0 +1,6 @@
+-- Quiz lifecycle: existing quizzes stay published, new rows start as drafts
+ALTER TABLE quiz_questions ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'published'
+    CHECK (status IN ('draft', 'published', 'archived'));
+ALTER TABLE quiz_questions ALTER COLUMN status SET DEFAULT 'draft';
+
+CREATE INDEX IF NOT EXISTS quiz_questions_status_idx ON quiz_questions (status);
@@ -0,0 +1,14 @@
+-- Reusable questions tagged by category, topic and difficulty for assembling quizzes
+CREATE TABLE IF NOT EXISTS question_bank (
+    id            SERIAL PRIMARY KEY,
+    question_hash TEXT        NOT NULL UNIQUE,
+    category      TEXT        NOT NULL,
+    topic         TEXT        NOT NULL DEFAULT '',
+    difficulty    TEXT        NOT NULL DEFAULT '',
+    question      JSONB       NOT NULL,
+    uploaded_by   TEXT        NOT NULL,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS question_bank_lookup_idx ON question_bank (category, topic, difficulty);
@@ -0,0 +1,4 @@
+-- Per-quiz marking scheme; NULL means the quiz has no scoring config
+ALTER TABLE quiz_questions ADD COLUMN IF NOT EXISTS correct_marks    NUMERIC;
+ALTER TABLE quiz_questions ADD COLUMN IF NOT EXISTS wrong_penalty    NUMERIC;
+ALTER TABLE quiz_questions ADD COLUMN IF NOT EXISTS unanswered_marks NUMERIC;
@@ -0,0 +1,12 @@
+-- Content change feed read by GET /changes for incremental sync
+CREATE TABLE IF NOT EXISTS content_changes (
+    id          BIGSERIAL PRIMARY KEY,
+    change_type TEXT        NOT NULL,
+    quiz_name   TEXT        NOT NULL,
+    category    TEXT        NOT NULL DEFAULT '',
+    detail      TEXT,
+    changed_by  TEXT        NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS content_changes_created_at_idx ON content_changes (created_at);
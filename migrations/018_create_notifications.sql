@@ -0,0 +1,12 @@
+-- Pending notifications (e.g. a staff reply to a doubt), picked up by the sender
+CREATE TABLE IF NOT EXISTS notifications (
+    id              SERIAL PRIMARY KEY,
+    recipient_email TEXT        NOT NULL,
+    kind            TEXT        NOT NULL,
+    doubt_id        INTEGER     REFERENCES doubts (id) ON DELETE CASCADE,
+    body            TEXT        NOT NULL DEFAULT '',
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    sent_at         TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS notifications_pending_idx ON notifications (created_at) WHERE sent_at IS NULL;
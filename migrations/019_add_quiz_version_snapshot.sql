@@ -0,0 +1,9 @@
+-- Snapshot metadata, scoring and publish window with each version so rollback
+-- restores all of them. metadata is NULL only on versions saved before this
+-- migration; rollback keeps the current values for those
+ALTER TABLE quiz_versions ADD COLUMN IF NOT EXISTS metadata         JSONB;
+ALTER TABLE quiz_versions ADD COLUMN IF NOT EXISTS correct_marks    NUMERIC;
+ALTER TABLE quiz_versions ADD COLUMN IF NOT EXISTS wrong_penalty    NUMERIC;
+ALTER TABLE quiz_versions ADD COLUMN IF NOT EXISTS unanswered_marks NUMERIC;
+ALTER TABLE quiz_versions ADD COLUMN IF NOT EXISTS publish_at       TIMESTAMPTZ;
+ALTER TABLE quiz_versions ADD COLUMN IF NOT EXISTS close_at         TIMESTAMPTZ;